@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/urfave/cli/v2"
 
+	"github.com/livekit/livekit-cli/pkg/config"
 	"github.com/livekit/livekit-cli/pkg/loadtester"
 	"github.com/livekit/protocol/logger"
 	lksdk "github.com/livekit/server-sdk-go"
@@ -20,7 +25,93 @@ var LoadTestCommands = []*cli.Command{
 		Usage:    "Run load tests against LiveKit with simulated publishers & subscribers",
 		Category: "Simulate",
 		Action:   loadTest,
+		Subcommands: []*cli.Command{
+			{
+				Name:   "coordinator",
+				Usage:  "Split a scenario between agents, start them together and merge their results",
+				Action: loadTestCoordinator,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:      "scenario",
+						Usage:     "YAML scenario file describing the whole test",
+						TakesFile: true,
+						Required:  true,
+					},
+					&cli.IntFlag{
+						Name:  "agents",
+						Usage: "number of agents to wait for before starting",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "address to accept agents on",
+						Value: ":7801",
+					},
+					&cli.StringFlag{
+						Name:      "junit-file",
+						Usage:     "write merged results and thresholds as JUnit XML to this file",
+						TakesFile: true,
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "also export merged results as json or csv, to --output-file or stdout",
+					},
+					&cli.StringFlag{
+						Name:      "output-file",
+						Usage:     "file to export results to",
+						TakesFile: true,
+					},
+				},
+			},
+			{
+				Name:      "compare",
+				Usage:     "Compare results exported with --output json, flagging metrics that got worse",
+				ArgsUsage: "baseline.json candidate.json",
+				Action:    loadTestCompare,
+				Flags: []cli.Flag{
+					&cli.Float64Flag{
+						Name:  "tolerance",
+						Usage: "percent that bitrate and latency may get worse by before it counts as a regression",
+						Value: 5,
+					},
+					&cli.Float64Flag{
+						Name:  "drop-tolerance",
+						Usage: "percentage points that the share of dropped packets may rise by",
+						Value: 0.5,
+					},
+				},
+			},
+			{
+				Name:   "agent",
+				Usage:  "Run the part of a load test assigned by a coordinator",
+				Action: loadTestAgent,
+				Flags: withDefaultFlags(
+					&cli.StringFlag{
+						Name:  "coordinator",
+						Usage: "address of the coordinator",
+						Value: "localhost:7801",
+					},
+					&cli.StringFlag{
+						Name:  "name",
+						Usage: "name of this agent (defaults to the host name)",
+					},
+					&cli.StringFlag{
+						Name:  "metrics-addr",
+						Usage: "serve Prometheus metrics of the running test on this address, e.g. :9090",
+					},
+					&cli.StringFlag{
+						Name:  "media-dir",
+						Usage: "media directory on this machine, when it is not where the coordinator has it",
+					},
+				),
+			},
+		},
 		Flags: withDefaultFlags(
+			&cli.StringFlag{
+				Name:      "scenario",
+				Usage:     "YAML scenario file describing the test, flags set on the command line override its values",
+				TakesFile: true,
+			},
 			&cli.StringFlag{
 				Name:  "room",
 				Usage: "name of the room (default to random name)",
@@ -86,12 +177,33 @@ var LoadTestCommands = []*cli.Command{
 			},
 			&cli.StringFlag{
 				Name:  "video-resolution",
-				Usage: "resolution of video to publish. valid values are: 360p, 720p, 1080p, 1440p (default: 1080p)",
-				Value: "1080p",
+				Usage: "resolution of video to publish. valid values are: 360p, 720p, 1080p, 1440p (default: 1080p), or the resolutions of the --media-dir manifest (default: its first video)",
+			},
+			&cli.BoolFlag{
+				Name:  "synthetic",
+				Usage: "publish synthetic frames instead of video, to measure latency and loss at any bitrate without H.264",
+			},
+			&cli.IntFlag{
+				Name:  "synthetic-bitrate",
+				Usage: "bitrate in kbps of synthetic publishers (default: 2000)",
+			},
+			&cli.IntFlag{
+				Name:  "synthetic-fps",
+				Usage: "frame rate of synthetic publishers (default: 30)",
+			},
+			&cli.StringFlag{
+				Name:  "media-dir",
+				Usage: "directory with a manifest.yaml of videos to publish, instead of the built-in ones. --video-resolution selects its ladders by name",
+			},
+			&cli.StringFlag{
+				Name: "clock-reference",
+				Usage: "NTP server (host[:port]) or coordinator URL whose clock send times and latencies use, " +
+					"for publishers and subscribers on different machines. agents default to their coordinator",
 			},
 			&cli.StringFlag{
-				Name:  "video-codec",
-				Usage: "h264 or vp8, both will be used when unset",
+				Name: "video-codec",
+				Usage: "h264, or several separated by spaces that publishers take turns using (default: h264). " +
+					"vp8 and vp9 are published from the videos of a --media-dir manifest, the built-in videos are h264 only",
 			},
 			&cli.StringFlag{
 				Name:  "room-name",
@@ -103,9 +215,9 @@ var LoadTestCommands = []*cli.Command{
 				Value: 5,
 			},
 			&cli.StringFlag{
-				Name:  "layout",
-				Usage: "layout to simulate, choose from speaker, 3x3, 4x4, 5x5",
-				Value: "speaker",
+				Name: "layout",
+				Usage: "layout subscribers simulate in a --same-room test, choose from speaker, 3x3, 4x4, 5x5. " +
+					"by default every track is received at full quality",
 			},
 			&cli.BoolFlag{
 				Name:  "no-simulcast",
@@ -115,10 +227,112 @@ var LoadTestCommands = []*cli.Command{
 				Name:  "simulate-speakers",
 				Usage: "fire random speaker events to simulate speaker changes",
 			},
+			&cli.StringSliceFlag{
+				Name: "phase",
+				Usage: "staged load as name:duration:subscribers, e.g. ramp-up:20m:1000. subscribers per room move linearly " +
+					"to the target over each phase, can be used multiple times, replaces --duration and --subscribers",
+			},
+			&cli.Float64Flag{
+				Name:  "churn-subscriber-rate",
+				Usage: "subscribers that leave and are replaced by new ones per minute, across all rooms",
+			},
+			&cli.Float64Flag{
+				Name:  "churn-publisher-rate",
+				Usage: "publishers that leave and are replaced by new ones per minute, across all rooms",
+			},
+			&cli.DurationFlag{
+				Name:  "churn-subscriber-lifetime",
+				Usage: "mean time a subscriber stays before it is replaced, lifetimes are exponentially distributed",
+			},
+			&cli.DurationFlag{
+				Name:  "churn-publisher-lifetime",
+				Usage: "mean time a publisher stays before it is replaced, lifetimes are exponentially distributed",
+			},
+			&cli.DurationFlag{
+				Name:  "max-latency",
+				Usage: "fail when the average latency of any kind in a room is above this",
+			},
+			&cli.Float64Flag{
+				Name:  "max-drop-percent",
+				Usage: "fail when more than this percentage of packets of any kind in a room was dropped",
+			},
+			&cli.StringSliceFlag{
+				Name: "min-bitrate-ratio",
+				Usage: "fail when received bitrate is below this ratio of the published bitrate, as kind:ratio, " +
+					"e.g. video:0.8, for video or data, can be used multiple times",
+			},
+			&cli.IntFlag{
+				Name:  "max-errors",
+				Usage: "fail when more testers than this failed in a room",
+			},
+			&cli.StringFlag{
+				Name:      "junit-file",
+				Usage:     "write results and thresholds as JUnit XML to this file",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: "also export results as json or csv, to --output-file or stdout",
+			},
+			&cli.StringFlag{
+				Name:      "output-file",
+				Usage:     "file to export results to",
+				TakesFile: true,
+			},
+			&cli.DurationFlag{
+				Name:  "warm-up",
+				Usage: "discard stats of the first part of the test, e.g. 30s, --duration starts after it",
+			},
 			&cli.BoolFlag{
-				Name:   "run-all",
-				Usage:  "runs set list of load test cases",
-				Hidden: true,
+				Name:  "report-warm-up",
+				Usage: "report stats of the warm-up separately, like a phase",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "also measure bitrate, packet rate, latency and drops of every track per interval, e.g. 10s, to find stalls and degradation over time",
+			},
+			&cli.StringFlag{
+				Name:  "metrics-addr",
+				Usage: "serve Prometheus metrics on this address while the test is running, e.g. :9090",
+			},
+			&cli.BoolFlag{
+				Name:  "dashboard",
+				Usage: "show live per room bitrate, latency, drops, reconnects and errors every second while the test is running",
+			},
+			&cli.StringFlag{
+				Name: "search",
+				Usage: "finds the highest load that meets the thresholds, running one test of --duration per step. " +
+					"step raises the load by --search-step until a step fails, binary bisects between --search-start and --search-max",
+			},
+			&cli.StringFlag{
+				Name:  "search-target",
+				Usage: "participants to raise during --search, subscribers (per room) or publishers",
+				Value: "subscribers",
+			},
+			&cli.IntFlag{
+				Name:  "search-start",
+				Usage: "load of the first --search step",
+				Value: 10,
+			},
+			&cli.IntFlag{
+				Name:  "search-step",
+				Usage: "load added per --search step, or the precision of a binary search",
+				Value: 10,
+			},
+			&cli.IntFlag{
+				Name:  "search-max",
+				Usage: "highest load tried by --search",
+				Value: 1000,
+			},
+			&cli.BoolFlag{
+				Name: "run-all",
+				Usage: "runs the built-in benchmark suite (1:1 call, 10-publisher meeting, 1-to-500 webinar, data-heavy room) " +
+					"one case after another, each for --duration (default 1m)",
+			},
+			&cli.DurationFlag{
+				Name:  "cool-down",
+				Usage: "with --run-all or --search, time to wait between cases or steps",
+				Value: 10 * time.Second,
 			},
 			&cli.BoolFlag{
 				Name:   "same-room",
@@ -140,6 +354,140 @@ func loadTest(cCtx *cli.Context) error {
 		return err
 	}
 
+	ctx := loadTestContext(cCtx)
+
+	params, err := loadTestParams(cCtx, pc)
+	if err != nil {
+		return err
+	}
+
+	test := loadtester.NewLoadTest(params)
+	test.Out = loadTestOut(params)
+	if cCtx.IsSet("search") {
+		mode, err := loadtester.ParseSearchMode(cCtx.String("search"))
+		if err != nil {
+			return err
+		}
+		target, err := loadtester.ParseSearchTarget(cCtx.String("search-target"))
+		if err != nil {
+			return err
+		}
+		return loadTestExit(test.RunSearch(ctx, loadtester.SearchParams{
+			Mode:     mode,
+			Target:   target,
+			Start:    cCtx.Int("search-start"),
+			Step:     cCtx.Int("search-step"),
+			Max:      cCtx.Int("search-max"),
+			CoolDown: cCtx.Duration("cool-down"),
+		}))
+	}
+
+	if cCtx.Bool("run-all") {
+		duration := params.Duration
+		if duration == 0 {
+			duration = time.Minute
+		}
+		return loadTestExit(test.RunSuite(ctx, loadtester.BenchmarkSuite(duration), cCtx.Duration("cool-down")))
+	}
+
+	return loadTestExit(test.Run(ctx))
+}
+
+// loadTestOutput is the export format, taken from the output file's extension when only the file is given
+func loadTestOutput(cCtx *cli.Context) (loadtester.OutputFormat, error) {
+	format := cCtx.String("output")
+	if format == "" && cCtx.String("output-file") != "" {
+		format = string(loadtester.OutputJSON)
+		if strings.HasSuffix(strings.ToLower(cCtx.String("output-file")), ".csv") {
+			format = string(loadtester.OutputCSV)
+		}
+	}
+
+	return loadtester.ParseOutputFormat(format)
+}
+
+// loadTestOut is where tables and progress go, stderr when stdout is left to an export so that it can be piped
+func loadTestOut(params loadtester.Params) io.Writer {
+	if params.Output != loadtester.OutputNone && params.OutputFile == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// loadTestExit makes failed thresholds and regressions exit with a non-zero status
+func loadTestExit(err error) error {
+	var thresholdErr *loadtester.ThresholdError
+	var regressionErr *loadtester.RegressionError
+	if errors.As(err, &thresholdErr) || errors.As(err, &regressionErr) {
+		return cli.Exit(err.Error(), 1)
+	}
+
+	return err
+}
+
+func loadTestCoordinator(cCtx *cli.Context) error {
+	scenario, err := loadtester.LoadScenario(cCtx.String("scenario"))
+	if err != nil {
+		return err
+	}
+
+	params := scenario.Params()
+	params.JUnitFile = cCtx.String("junit-file")
+	if params.Output, err = loadTestOutput(cCtx); err != nil {
+		return err
+	}
+	params.OutputFile = cCtx.String("output-file")
+
+	coordinator, err := loadtester.NewCoordinator(loadtester.CoordinatorParams{
+		Address: cCtx.String("listen"),
+		Agents:  cCtx.Int("agents"),
+		Params:  params,
+		Out:     loadTestOut(params),
+	})
+	if err != nil {
+		return err
+	}
+
+	return loadTestExit(coordinator.Run(loadTestContext(cCtx)))
+}
+
+func loadTestCompare(cCtx *cli.Context) error {
+	if cCtx.NArg() != 2 {
+		return errors.New("expected a baseline and a candidate result file")
+	}
+
+	return loadTestExit(loadtester.CompareResults(cCtx.Args().Get(0), cCtx.Args().Get(1), loadtester.CompareParams{
+		Tolerance:     cCtx.Float64("tolerance"),
+		DropTolerance: cCtx.Float64("drop-tolerance"),
+	}))
+}
+
+func loadTestAgent(cCtx *cli.Context) error {
+	pc, err := loadProjectDetails(cCtx)
+	if err != nil {
+		return err
+	}
+
+	name := cCtx.String("name")
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	agent := loadtester.NewAgent(loadtester.AgentParams{
+		CoordinatorURL: cCtx.String("coordinator"),
+		Name:           name,
+		URL:            pc.URL,
+		APIKey:         pc.APIKey,
+		APISecret:      pc.APISecret,
+		MetricsAddr:    cCtx.String("metrics-addr"),
+		MediaDir:       cCtx.String("media-dir"),
+	})
+
+	return agent.Run(loadTestContext(cCtx))
+}
+
+// loadTestContext prepares the process for running testers, the returned context is canceled on termination signals
+func loadTestContext(cCtx *cli.Context) context.Context {
 	if !cCtx.Bool("verbose") {
 		lksdk.SetLogger(logger.LogRLogger(logr.Discard()))
 	}
@@ -153,36 +501,184 @@ func loadTest(cCtx *cli.Context) error {
 		cancel()
 	}()
 
-	params := loadtester.Params{
-		VideoResolution:   cCtx.String("video-resolution"),
-		VideoCodec:        cCtx.String("video-codec"),
-		Duration:          cCtx.Duration("duration"),
-		NumPerSecond:      cCtx.Float64("num-per-second"),
-		Simulcast:         !cCtx.Bool("no-simulcast"),
-		SameRoom:          cCtx.Bool("same-room"),
-		WithAudio:         cCtx.Bool("with-audio"),
-		SimulateSpeakers:  cCtx.Bool("simulate-speakers"),
-		HighQualityViewer: cCtx.Int("high"),
-		MediumQualityView: cCtx.Int("medium"),
-		LowQualityViewer:  cCtx.Int("low"),
-		TesterParams: loadtester.TesterParams{
-			URL:            pc.URL,
-			APIKey:         pc.APIKey,
-			APISecret:      pc.APISecret,
-			Room:           cCtx.String("room-name"),
-			IdentityPrefix: cCtx.String("identity-prefix"),
-		},
-		StartPublisher:        cCtx.Int("start-publisher"),
-		EndPublisher:          cCtx.Int("end-publisher"),
-		StartRemoteRoomNumber: cCtx.Int("start-room-number"),
-		EndRemoteRoomNumber:   cCtx.Int("end-room-number"),
-		DataPublishers:        cCtx.Int("data-publishers"),
-		Subscribers:           cCtx.Int("subscribers"),
-		DataPacketByteSize:    cCtx.Int("data-packet-bytes"),
-		DataBitrate:           cCtx.Int("data-bitrate") * 1024,
-		RemotePublishers:      cCtx.Int("remote-publisher"),
+	return ctx
+}
+
+// loadTestParams builds test params from the scenario file when one is given,
+// with any flag explicitly set on the command line taking precedence.
+// Without a scenario, every flag (including its default) is used.
+func loadTestParams(cCtx *cli.Context, pc *config.ProjectConfig) (loadtester.Params, error) {
+	var params loadtester.Params
+	fromScenario := false
+	if path := cCtx.String("scenario"); path != "" {
+		scenario, err := loadtester.LoadScenario(path)
+		if err != nil {
+			return params, err
+		}
+		params = scenario.Params()
+		fromScenario = true
 	}
 
-	test := loadtester.NewLoadTest(params)
-	return test.Run(ctx)
+	use := func(name string) bool {
+		return !fromScenario || cCtx.IsSet(name)
+	}
+
+	if use("video-resolution") {
+		params.VideoResolution = cCtx.String("video-resolution")
+	}
+	if use("video-codec") {
+		params.VideoCodec = cCtx.String("video-codec")
+	}
+	if use("duration") {
+		params.Duration = cCtx.Duration("duration")
+	}
+	if use("interval") {
+		params.Interval = cCtx.Duration("interval")
+	}
+	if use("synthetic") {
+		params.Synthetic = cCtx.Bool("synthetic")
+	}
+	if use("synthetic-bitrate") {
+		params.SyntheticBitrate = cCtx.Int("synthetic-bitrate") * 1024
+	}
+	if use("synthetic-fps") {
+		params.SyntheticFPS = cCtx.Int("synthetic-fps")
+	}
+	if use("media-dir") {
+		params.MediaDir = cCtx.String("media-dir")
+	}
+	if use("clock-reference") {
+		params.ClockReference = cCtx.String("clock-reference")
+	}
+	if use("warm-up") {
+		params.WarmUp = cCtx.Duration("warm-up")
+	}
+	if use("report-warm-up") {
+		params.ReportWarmUp = cCtx.Bool("report-warm-up")
+	}
+	if use("num-per-second") {
+		params.NumPerSecond = cCtx.Float64("num-per-second")
+	}
+	if use("no-simulcast") {
+		params.Simulcast = !cCtx.Bool("no-simulcast")
+	}
+	if use("same-room") {
+		params.SameRoom = cCtx.Bool("same-room")
+	}
+	if use("with-audio") {
+		params.WithAudio = cCtx.Bool("with-audio")
+	}
+	if use("simulate-speakers") {
+		params.SimulateSpeakers = cCtx.Bool("simulate-speakers")
+	}
+	if use("high") {
+		params.HighQualityViewer = cCtx.Int("high")
+	}
+	if use("medium") {
+		params.MediumQualityView = cCtx.Int("medium")
+	}
+	if use("low") {
+		params.LowQualityViewer = cCtx.Int("low")
+	}
+	if use("room-name") {
+		params.Room = cCtx.String("room-name")
+	}
+	if use("identity-prefix") {
+		params.IdentityPrefix = cCtx.String("identity-prefix")
+	}
+	if use("start-publisher") {
+		params.StartPublisher = cCtx.Int("start-publisher")
+	}
+	if use("end-publisher") {
+		params.EndPublisher = cCtx.Int("end-publisher")
+	}
+	if use("start-room-number") {
+		params.StartRemoteRoomNumber = cCtx.Int("start-room-number")
+	}
+	if use("end-room-number") {
+		params.EndRemoteRoomNumber = cCtx.Int("end-room-number")
+	}
+	if use("data-publishers") {
+		params.DataPublishers = cCtx.Int("data-publishers")
+	}
+	if use("subscribers") {
+		params.Subscribers = cCtx.Int("subscribers")
+	}
+	if use("data-packet-bytes") {
+		params.DataPacketByteSize = cCtx.Int("data-packet-bytes")
+	}
+	if use("data-bitrate") {
+		params.DataBitrate = cCtx.Int("data-bitrate") * 1024
+	}
+
+	if use("layout") {
+		layout, err := loadtester.ParseLayout(cCtx.String("layout"))
+		if err != nil {
+			return params, err
+		}
+		if layout != loadtester.LayoutNone && !params.SameRoom {
+			return params, errors.New("--layout requires --same-room")
+		}
+		params.Layout = layout
+	}
+	if use("phase") && len(cCtx.StringSlice("phase")) > 0 {
+		params.Phases = nil
+		for _, p := range cCtx.StringSlice("phase") {
+			phase, err := loadtester.ParsePhase(p)
+			if err != nil {
+				return params, err
+			}
+			params.Phases = append(params.Phases, phase)
+		}
+	}
+
+	if use("churn-subscriber-rate") {
+		params.Churn.SubscriberRate = cCtx.Float64("churn-subscriber-rate")
+	}
+	if use("churn-publisher-rate") {
+		params.Churn.PublisherRate = cCtx.Float64("churn-publisher-rate")
+	}
+	if use("churn-subscriber-lifetime") {
+		params.Churn.SubscriberLifetime = cCtx.Duration("churn-subscriber-lifetime")
+	}
+	if use("churn-publisher-lifetime") {
+		params.Churn.PublisherLifetime = cCtx.Duration("churn-publisher-lifetime")
+	}
+
+	if cCtx.IsSet("max-latency") {
+		params.Thresholds.MaxLatency = cCtx.Duration("max-latency")
+	}
+	if cCtx.IsSet("max-drop-percent") {
+		maxDrop := cCtx.Float64("max-drop-percent")
+		params.Thresholds.MaxDropPercent = &maxDrop
+	}
+	if cCtx.IsSet("min-bitrate-ratio") {
+		params.Thresholds.MinBitrateRatio = make(map[loadtester.TrackKind]float64)
+		for _, r := range cCtx.StringSlice("min-bitrate-ratio") {
+			kind, ratio, err := loadtester.ParseBitrateRatio(r)
+			if err != nil {
+				return params, err
+			}
+			params.Thresholds.MinBitrateRatio[kind] = ratio
+		}
+	}
+	if cCtx.IsSet("max-errors") {
+		maxErrors := cCtx.Int("max-errors")
+		params.Thresholds.MaxErrors = &maxErrors
+	}
+	params.JUnitFile = cCtx.String("junit-file")
+	output, err := loadTestOutput(cCtx)
+	if err != nil {
+		return params, err
+	}
+	params.Output = output
+	params.OutputFile = cCtx.String("output-file")
+	params.Dashboard = cCtx.Bool("dashboard")
+	params.MetricsAddr = cCtx.String("metrics-addr")
+
+	params.URL = pc.URL
+	params.APIKey = pc.APIKey
+	params.APISecret = pc.APISecret
+
+	return params, nil
 }
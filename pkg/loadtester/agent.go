@@ -0,0 +1,143 @@
+package loadtester
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AgentParams struct {
+	// coordinator base URL, e.g. http://localhost:7801
+	CoordinatorURL string
+	// name reported to the coordinator, defaults to the host name
+	Name string
+	// connection details for the LiveKit server, these are never sent by the coordinator
+	URL       string
+	APIKey    string
+	APISecret string
+	// address to serve Prometheus metrics on while running, e.g. :9090
+	MetricsAddr string
+	// media directory of this machine, when it is not where the coordinator has it
+	MediaDir string
+}
+
+// Agent runs the part of a test assigned to it by a Coordinator
+type Agent struct {
+	params AgentParams
+	client *http.Client
+}
+
+func NewAgent(params AgentParams) *Agent {
+	params.CoordinatorURL = strings.TrimRight(params.CoordinatorURL, "/")
+	if !strings.Contains(params.CoordinatorURL, "://") {
+		params.CoordinatorURL = "http://" + params.CoordinatorURL
+	}
+
+	return &Agent{
+		params: params,
+		client: &http.Client{},
+	}
+}
+
+// Run registers with the coordinator, waits for the common start time, runs the assigned test and reports results
+func (a *Agent) Run(ctx context.Context) error {
+	reg := &registerResponse{}
+	if err := a.do(ctx, http.MethodPost, coordinatorRegisterPath, &registerRequest{Name: a.params.Name}, reg); err != nil {
+		return fmt.Errorf("could not register with coordinator: %w", err)
+	}
+
+	fmt.Printf("Registered with coordinator as agent %d, waiting for other agents\n", reg.ID)
+
+	agentQuery := fmt.Sprintf("?agent=%d", reg.ID)
+	assignment := &AgentAssignment{}
+	if err := a.do(ctx, http.MethodGet, coordinatorAssignmentPath+agentQuery, nil, assignment); err != nil {
+		return fmt.Errorf("could not get assignment: %w", err)
+	}
+
+	params := assignment.Params
+	params.URL = a.params.URL
+	params.APIKey = a.params.APIKey
+	params.APISecret = a.params.APISecret
+	params.MetricsAddr = a.params.MetricsAddr
+	if a.params.MediaDir != "" {
+		params.MediaDir = a.params.MediaDir
+	}
+	// agents measure latency across machines, the coordinator is a reference they can all reach
+	if params.ClockReference == "" {
+		params.ClockReference = a.params.CoordinatorURL
+	}
+
+	fmt.Printf("Starting in %s\n", assignment.StartIn.Round(time.Millisecond))
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(assignment.StartIn):
+	}
+
+	test := NewLoadTest(params)
+	res := &AgentResult{
+		Agent: a.params.Name,
+	}
+	result, err := test.run(ctx, test.Params)
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Testers = testerResults(result.stats)
+		res.Phases = phaseResults(result.phases)
+		res.Publishers = testerResults(result.publishers)
+		res.Clock = result.clock
+	}
+
+	// results are still reported when canceled, the test context is done by now
+	if err := a.do(context.Background(), http.MethodPost, coordinatorResultsPath+agentQuery, res, nil); err != nil {
+		return fmt.Errorf("could not report results: %w", err)
+	}
+
+	if result != nil && test.Params.Subscribers > 0 {
+		test.printStats(result.stats)
+		test.printJoins(result.stats, result.publishers)
+		test.printPublishers(result.publishers)
+		printClocks(test.Out, result.clocks(), result.stats)
+	}
+
+	return err
+}
+
+func (a *Agent) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.params.CoordinatorURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
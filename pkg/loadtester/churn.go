@@ -0,0 +1,301 @@
+package loadtester
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"golang.org/x/sync/syncmap"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const churnInterval = 100 * time.Millisecond
+
+// ChurnParams makes testers leave and get replaced by new ones while a test holds its load.
+// Churn can be set as a rate, or as a mean lifetime after which each tester is replaced.
+// Lifetimes are exponentially distributed, i.e. leaves happen at random like in a real room.
+type ChurnParams struct {
+	// testers replaced per minute, across all rooms
+	SubscriberRate float64
+	PublisherRate  float64
+	// mean time a tester stays connected, used when the rate is not set
+	SubscriberLifetime time.Duration
+	PublisherLifetime  time.Duration
+}
+
+func (c ChurnParams) enabled() bool {
+	return c.SubscriberRate > 0 || c.PublisherRate > 0 || c.SubscriberLifetime > 0 || c.PublisherLifetime > 0
+}
+
+type churnEntry struct {
+	tester    *LoadTester
+	expiresAt time.Time
+
+	// subscribers
+	subParam    *trackParams
+	quality     livekit.VideoQuality
+	publishData bool
+
+	// publishers
+	room       string
+	roomID     int
+	resolution string
+	codec      string
+}
+
+type churnPolicy struct {
+	interval time.Duration
+	lifetime time.Duration
+	nextAt   time.Time
+}
+
+func newChurnPolicy(rate float64, lifetime time.Duration) churnPolicy {
+	p := churnPolicy{}
+	if rate > 0 {
+		p.interval = time.Duration(float64(time.Minute) / rate)
+	} else {
+		p.lifetime = lifetime
+	}
+
+	return p
+}
+
+func (p *churnPolicy) enabled() bool {
+	return p.interval > 0 || p.lifetime > 0
+}
+
+func (p *churnPolicy) expiresAt(now time.Time) time.Time {
+	if p.lifetime == 0 {
+		return time.Time{}
+	}
+
+	return now.Add(time.Duration(rand.ExpFloat64() * float64(p.lifetime)))
+}
+
+// due returns the entries to replace now, at most one per tick for rates and every expired one for lifetimes
+func (p *churnPolicy) due(now time.Time, entries []*churnEntry) []int {
+	if p.interval > 0 {
+		if len(entries) == 0 || now.Before(p.nextAt) {
+			return nil
+		}
+		p.nextAt = p.nextAt.Add(p.interval)
+		return []int{rand.Intn(len(entries))}
+	}
+
+	var due []int
+	for i, e := range entries {
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			due = append(due, i)
+		}
+	}
+
+	return due
+}
+
+type churner struct {
+	params  Params
+	errs    *syncmap.Map
+	metrics *metricsCollector
+	fuse    core.Fuse
+	wg      sync.WaitGroup
+
+	lock        sync.Mutex
+	subPolicy   churnPolicy
+	pubPolicy   churnPolicy
+	subscribers []*churnEntry
+	publishers  []*churnEntry
+	// testers that joined as replacements
+	newSubscribers []*LoadTester
+	newPublishers  []*LoadTester
+	// next sequence number per room, so replacements get unique identities
+	subSeq map[string]int
+	pubSeq int
+}
+
+func newChurner(params Params, errs *syncmap.Map, metrics *metricsCollector) *churner {
+	return &churner{
+		params:    params,
+		errs:      errs,
+		metrics:   metrics,
+		subPolicy: newChurnPolicy(params.Churn.SubscriberRate, params.Churn.SubscriberLifetime),
+		pubPolicy: newChurnPolicy(params.Churn.PublisherRate, params.Churn.PublisherLifetime),
+		subSeq:    make(map[string]int),
+		pubSeq:    params.VideoPublishers,
+	}
+}
+
+func (c *churner) addSubscriber(tester *LoadTester, subParam *trackParams, quality livekit.VideoQuality, publishData bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.subscribers = append(c.subscribers, &churnEntry{
+		tester:      tester,
+		subParam:    subParam,
+		quality:     quality,
+		publishData: publishData,
+	})
+	if c.subSeq[subParam.roomName] <= tester.params.Sequence {
+		c.subSeq[subParam.roomName] = tester.params.Sequence + 1
+	}
+}
+
+func (c *churner) addPublisher(tester *LoadTester, room string, roomID int, resolution, codec string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.publishers = append(c.publishers, &churnEntry{
+		tester:     tester,
+		room:       room,
+		roomID:     roomID,
+		resolution: resolution,
+		codec:      codec,
+	})
+}
+
+func (c *churner) Start() {
+	if c.fuse != nil {
+		return
+	}
+
+	now := time.Now()
+	c.lock.Lock()
+	c.subPolicy.nextAt = now.Add(c.subPolicy.interval)
+	c.pubPolicy.nextAt = now.Add(c.pubPolicy.interval)
+	for _, e := range c.subscribers {
+		e.expiresAt = c.subPolicy.expiresAt(now)
+	}
+	for _, e := range c.publishers {
+		e.expiresAt = c.pubPolicy.expiresAt(now)
+	}
+	c.lock.Unlock()
+
+	c.fuse = core.NewFuse()
+	go c.worker()
+}
+
+// Stop ends churning and waits for replacements that are still joining
+func (c *churner) Stop() {
+	if c.fuse == nil || c.fuse.IsBroken() {
+		return
+	}
+	c.fuse.Break()
+	c.wg.Wait()
+}
+
+func (c *churner) worker() {
+	t := time.NewTicker(churnInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.fuse.Watch():
+			return
+		case now := <-t.C:
+			c.lock.Lock()
+			if c.subPolicy.enabled() {
+				for _, i := range c.subPolicy.due(now, c.subscribers) {
+					c.subscribers[i] = c.replaceSubscriber(c.subscribers[i], now)
+				}
+			}
+			if c.pubPolicy.enabled() {
+				for _, i := range c.pubPolicy.due(now, c.publishers) {
+					c.publishers[i] = c.replacePublisher(c.publishers[i], now)
+				}
+			}
+			c.lock.Unlock()
+		}
+	}
+}
+
+func (c *churner) replaceSubscriber(e *churnEntry, now time.Time) *churnEntry {
+	seq := c.subSeq[e.subParam.roomName]
+	c.subSeq[e.subParam.roomName]++
+
+	tester := newSubscriber(c.params, e.subParam, seq, e.quality, c.errs)
+	if tester == nil {
+		return e
+	}
+	c.newSubscribers = append(c.newSubscribers, tester)
+	c.metrics.add(c.errs, tester)
+
+	old := e.tester
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		old.Stop()
+		startSubscriber(c.params, tester, e.publishData, closedChan(), c.errs)
+	}()
+
+	return &churnEntry{
+		tester:      tester,
+		expiresAt:   c.subPolicy.expiresAt(now),
+		subParam:    e.subParam,
+		quality:     e.quality,
+		publishData: e.publishData,
+	}
+}
+
+func (c *churner) replacePublisher(e *churnEntry, now time.Time) *churnEntry {
+	seq := c.pubSeq
+	c.pubSeq++
+
+	testerPubParams := prepareTesterPubParams(c.params, seq, e.room, e.roomID)
+	testerPubParams.name = fmt.Sprintf("Pub %d.%d", e.roomID, seq)
+	next := &churnEntry{
+		// until the new publisher has connected, the old one stands in for it
+		tester:     e.tester,
+		expiresAt:  c.pubPolicy.expiresAt(now),
+		room:       e.room,
+		roomID:     e.roomID,
+		resolution: e.resolution,
+		codec:      e.codec,
+	}
+
+	old := e.tester
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if old != nil {
+			old.Stop()
+		}
+		tester, err := startPublisher(c.params, testerPubParams, e.resolution, e.codec)
+		if err != nil {
+			c.errs.Store(testerPubParams.name, err)
+		}
+
+		c.lock.Lock()
+		next.tester = tester
+		if tester != nil {
+			c.newPublishers = append(c.newPublishers, tester)
+			c.metrics.add(c.errs, tester)
+		}
+		c.lock.Unlock()
+	}()
+
+	return next
+}
+
+func (c *churner) replacedSubscribers() []*LoadTester {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]*LoadTester{}, c.newSubscribers...)
+}
+
+func (c *churner) replacedPublishers() []*LoadTester {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]*LoadTester{}, c.newPublishers...)
+}
+
+func (c *churner) printSummary() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	fmt.Fprintf(c.params.out, "\rChurn replaced %d subscribers and %d publishers                   \n",
+		len(c.newSubscribers), len(c.newPublishers))
+}
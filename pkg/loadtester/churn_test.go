@@ -0,0 +1,39 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChurnPolicy(t *testing.T) {
+	now := time.Now()
+	entries := []*churnEntry{{}, {}, {}}
+
+	t.Run("rate", func(t *testing.T) {
+		p := newChurnPolicy(120, time.Minute)
+		require.Equal(t, 500*time.Millisecond, p.interval)
+		require.Zero(t, p.lifetime)
+		require.True(t, p.expiresAt(now).IsZero())
+
+		p.nextAt = now.Add(p.interval)
+		require.Empty(t, p.due(now, entries))
+		require.Len(t, p.due(now.Add(time.Second), entries), 1)
+		require.Equal(t, now.Add(2*p.interval), p.nextAt)
+	})
+
+	t.Run("lifetime", func(t *testing.T) {
+		p := newChurnPolicy(0, time.Minute)
+		require.True(t, p.enabled())
+
+		entries[0].expiresAt = now.Add(-time.Second)
+		entries[2].expiresAt = now.Add(time.Second)
+		require.Equal(t, []int{0}, p.due(now, entries))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p := newChurnPolicy(0, 0)
+		require.False(t, p.enabled())
+	})
+}
@@ -0,0 +1,216 @@
+package loadtester
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	coordinatorTimePath = "/time"
+
+	// exchanges per estimate, the one with the shortest round trip is used
+	clockExchanges = 8
+	// send times further off than this are taken for garbage rather than skew
+	maxClockSkew = 20 * time.Minute
+
+	// seconds from the NTP epoch (1900) to the Unix epoch
+	ntpEpochOffset = 2208988800
+)
+
+// ClockOffset is the estimated difference between a reference clock shared by all machines of a test and the local clock
+type ClockOffset struct {
+	// agent the offset was estimated by, empty for tests without coordinator
+	Agent     string `json:"agent,omitempty"`
+	Reference string `json:"reference"`
+	// reference minus local time
+	Offset time.Duration `json:"offset_ns"`
+	// half the round trip of the exchange the offset was taken from, the offset is off by at most this much
+	Uncertainty time.Duration `json:"uncertainty_ns"`
+}
+
+type timeResponse struct {
+	Received time.Time `json:"received"`
+	Sent     time.Time `json:"sent"`
+}
+
+// clockExchange returns the local send and receive time of a request, and the reference time it was received and answered at
+type clockExchange func(ctx context.Context) (t0, t1, t2, t3 time.Time, err error)
+
+// estimateClockOffset compares the local clock with reference, either a coordinator URL or an NTP server as host[:port].
+// Like NTP, the offset is taken from the exchange with the shortest round trip, which is the least skewed by queuing.
+func estimateClockOffset(ctx context.Context, reference string) (*ClockOffset, error) {
+	exchange := ntpExchange(reference)
+	if strings.Contains(reference, "://") {
+		exchange = httpExchange(reference)
+	}
+
+	var best *ClockOffset
+	var lastErr error
+	for i := 0; i < clockExchanges; i++ {
+		t0, t1, t2, t3, err := exchange(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		o := clockOffsetOf(t0, t1, t2, t3)
+		if best == nil || o.Uncertainty < best.Uncertainty {
+			best = o
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("could not estimate clock offset against %s: %w", reference, lastErr)
+	}
+
+	best.Reference = reference
+	return best, nil
+}
+
+func clockOffsetOf(t0, t1, t2, t3 time.Time) *ClockOffset {
+	roundTrip := t3.Sub(t0) - t2.Sub(t1)
+	if roundTrip < 0 {
+		roundTrip = 0
+	}
+
+	return &ClockOffset{
+		Offset:      (t1.Sub(t0) + t2.Sub(t3)) / 2,
+		Uncertainty: roundTrip / 2,
+	}
+}
+
+func httpExchange(url string) clockExchange {
+	url = strings.TrimRight(url, "/") + coordinatorTimePath
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	return func(ctx context.Context) (t0, t1, t2, t3 time.Time, err error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return
+		}
+
+		t0 = time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+
+		res := &timeResponse{}
+		err = json.NewDecoder(resp.Body).Decode(res)
+		t3 = time.Now()
+		if err == nil && resp.StatusCode >= http.StatusBadRequest {
+			err = errors.New(resp.Status)
+		}
+
+		return t0, res.Received, res.Sent, t3, err
+	}
+}
+
+// ntpExchange queries an NTP server with SNTP (RFC 4330)
+func ntpExchange(server string) clockExchange {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	return func(ctx context.Context) (t0, t1, t2, t3 time.Time, err error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "udp", server)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		req := make([]byte, 48)
+		// version 4, client mode
+		req[0] = 0x23
+
+		t0 = time.Now()
+		if _, err = conn.Write(req); err != nil {
+			return
+		}
+		res := make([]byte, 48)
+		n, err := conn.Read(res)
+		t3 = time.Now()
+		if err != nil {
+			return
+		}
+		if n < 48 || res[0]&0x07 != 4 {
+			err = errors.New("invalid NTP response")
+			return
+		}
+
+		return t0, ntpTime(res[32:40]), ntpTime(res[40:48]), t3, nil
+	}
+}
+
+func ntpTime(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(seconds, (fraction*int64(time.Second))>>32)
+}
+
+func handleTime(w http.ResponseWriter, _ *http.Request) {
+	received := time.Now()
+	writeJSON(w, &timeResponse{Received: received, Sent: time.Now()})
+}
+
+func (r *testResult) clocks() []*ClockOffset {
+	if r.clock == nil {
+		return nil
+	}
+	return []*ClockOffset{r.clock}
+}
+
+// recordSendTime records the latency of media sent at sentAt and received at now, both on the reference clock.
+// Send times further off than maxClockSkew, and latencies below zero, are counted as discarded.
+func (s *trackStats) recordSendTime(sentAt, now time.Time) {
+	latency := now.Sub(sentAt)
+	if latency <= 0 || latency > maxClockSkew {
+		s.latencyDiscarded.Inc()
+		return
+	}
+
+	s.recordLatency(latency.Nanoseconds())
+}
+
+// printClocks shows the clock offsets latencies were corrected by, and how many latency samples could not be used
+func printClocks(out io.Writer, clocks []*ClockOffset, stats map[string]map[string]*testerStats) {
+	var discarded int64
+	for _, roomStats := range stats {
+		for _, ts := range roomStats {
+			for _, s := range ts.stats {
+				discarded += s.latencyDiscarded.Load()
+			}
+		}
+	}
+
+	if len(clocks) > 0 {
+		w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+		_, _ = fmt.Fprint(w, "\nClock\t| Agent\t| Reference\t| Offset\t| Uncertainty\n")
+		for _, c := range clocks {
+			agent := c.Agent
+			if agent == "" {
+				agent = " - "
+			}
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %s\t| ±%s\n", agent, c.Reference,
+				c.Offset.Round(10*time.Microsecond), c.Uncertainty.Round(10*time.Microsecond))
+		}
+		_ = w.Flush()
+		fmt.Fprintln(out, "Latencies are on the reference clock, and off by up to the sum of the publisher's and subscriber's uncertainty.")
+	}
+
+	if discarded > 0 {
+		fmt.Fprintf(out, "\n%d latency samples were discarded, as they were negative or over %s. Clocks of publishers and subscribers may be apart, see --clock-reference.\n",
+			discarded, maxClockSkew)
+	}
+}
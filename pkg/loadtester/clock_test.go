@@ -0,0 +1,70 @@
+package loadtester
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockOffsetOf(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	// reference is 5s ahead, 10ms each way and 2ms to answer
+	t1 := t0.Add(5*time.Second + 10*time.Millisecond)
+	t2 := t1.Add(2 * time.Millisecond)
+	t3 := t0.Add(22 * time.Millisecond)
+
+	o := clockOffsetOf(t0, t1, t2, t3)
+	require.Equal(t, 5*time.Second, o.Offset)
+	require.Equal(t, 10*time.Millisecond, o.Uncertainty)
+}
+
+func TestEstimateClockOffset(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(coordinatorTimePath, handleTime)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	o, err := estimateClockOffset(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, server.URL, o.Reference)
+	// same clock on both ends
+	require.InDelta(t, 0, float64(o.Offset), float64(50*time.Millisecond))
+	require.Less(t, o.Uncertainty, 50*time.Millisecond)
+
+	_, err = estimateClockOffset(context.Background(), server.URL+"/missing")
+	require.Error(t, err)
+}
+
+func TestNTPTime(t *testing.T) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, ntpEpochOffset+1_700_000_000)
+	binary.BigEndian.PutUint32(b[4:], 1<<31)
+	require.Equal(t, time.Unix(1_700_000_000, int64(500*time.Millisecond)), ntpTime(b))
+}
+
+func TestRecordSendTime(t *testing.T) {
+	s := &trackStats{}
+	now := time.Now()
+
+	s.recordSendTime(now.Add(-20*time.Millisecond), now)
+	require.Equal(t, int64(1), s.latencyCount.Load())
+	require.Equal(t, (20 * time.Millisecond).Nanoseconds(), s.latency.Load())
+
+	// sent after it was received, or a long time ago
+	s.recordSendTime(now.Add(time.Second), now)
+	s.recordSendTime(now.Add(-time.Hour), now)
+	require.Equal(t, int64(1), s.latencyCount.Load())
+	require.Equal(t, int64(2), s.latencyDiscarded.Load())
+
+	prev := s.snapshot()
+	s.recordSendTime(now, now)
+	require.Equal(t, int64(1), s.snapshot().delta(prev).latencyDiscarded.Load())
+
+	s.reset()
+	require.Zero(t, s.latencyDiscarded.Load())
+}
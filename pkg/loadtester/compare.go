@@ -0,0 +1,205 @@
+package loadtester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+type CompareParams struct {
+	// relative change in percent that bitrate and latency may get worse by
+	Tolerance float64
+	// percentage points that the drop percentage may rise by
+	DropTolerance float64
+}
+
+// RegressionError is returned when a candidate result is worse than its baseline
+type RegressionError struct {
+	Regressions int
+}
+
+func (e *RegressionError) Error() string {
+	return fmt.Sprintf("%d metric(s) regressed", e.Regressions)
+}
+
+// comparedMetric is one metric of one room and kind in both results
+type comparedMetric struct {
+	room      string
+	kind      TrackKind
+	name      string
+	baseline  string
+	candidate string
+	delta     string
+	regressed bool
+}
+
+// LoadExport reads results exported as JSON
+func LoadExport(path string) (*Export, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Export{}
+	if err = json.Unmarshal(content, e); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON export: %w", path, err)
+	}
+
+	return e, nil
+}
+
+// CompareResults prints how the candidate results differ from the baseline,
+// returning a RegressionError when any metric got worse by more than the tolerance
+func CompareResults(baselinePath, candidatePath string, params CompareParams) error {
+	baseline, err := LoadExport(baselinePath)
+	if err != nil {
+		return err
+	}
+
+	candidate, err := LoadExport(candidatePath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Baseline:  %s, CLI %s, %s\n", baselinePath, baseline.Metadata.CLIVersion, baseline.Metadata.StartedAt.Format(time.RFC3339))
+	fmt.Printf("Candidate: %s, CLI %s, %s\n", candidatePath, candidate.Metadata.CLIVersion, candidate.Metadata.StartedAt.Format(time.RFC3339))
+
+	metrics, unmatched := compareExports(baseline, candidate, params)
+	printComparison(os.Stdout, metrics)
+	for _, u := range unmatched {
+		fmt.Println(u)
+	}
+
+	regressions := 0
+	for _, m := range metrics {
+		if m.regressed {
+			regressions++
+		}
+	}
+	if regressions > 0 {
+		return &RegressionError{Regressions: regressions}
+	}
+
+	fmt.Println("\nNo regressions")
+	return nil
+}
+
+// compareExports matches room totals by room and kind, in baseline order. Rooms and kinds
+// the candidate lacks count as regressions, the ones only the candidate has are returned as notes.
+func compareExports(baseline, candidate *Export, params CompareParams) ([]*comparedMetric, []string) {
+	candidateTotals := make(map[string]map[TrackKind]*SummaryExport)
+	for _, room := range candidate.Rooms {
+		candidateTotals[room.Room] = make(map[TrackKind]*SummaryExport)
+		for _, s := range room.Totals {
+			candidateTotals[room.Room][s.Kind] = s
+		}
+	}
+
+	var metrics []*comparedMetric
+	var unmatched []string
+	matched := make(map[string]bool)
+	for _, room := range baseline.Rooms {
+		totals, ok := candidateTotals[room.Room]
+		if ok {
+			matched[room.Room] = true
+		}
+
+		for _, b := range room.Totals {
+			c, ok := totals[b.Kind]
+			if !ok {
+				metrics = append(metrics, &comparedMetric{
+					room:      room.Room,
+					kind:      b.Kind,
+					name:      "totals",
+					baseline:  "present",
+					candidate: "missing",
+					delta:     "-",
+					regressed: true,
+				})
+				continue
+			}
+			metrics = append(metrics, compareSummaries(room.Room, b, c, params)...)
+		}
+	}
+
+	for _, room := range candidate.Rooms {
+		if !matched[room.Room] {
+			unmatched = append(unmatched, fmt.Sprintf("Room %s is only in the candidate", room.Room))
+		}
+	}
+
+	return metrics, unmatched
+}
+
+func compareSummaries(room string, b, c *SummaryExport, params CompareParams) []*comparedMetric {
+	metric := func(name, baseline, candidate, delta string, regressed bool) *comparedMetric {
+		return &comparedMetric{
+			room:      room,
+			kind:      b.Kind,
+			name:      name,
+			baseline:  baseline,
+			candidate: candidate,
+			delta:     delta,
+			regressed: regressed,
+		}
+	}
+
+	bitrateChange, bitrateRegressed := compareChange(b.Bitrate, c.Bitrate, params.Tolerance, false)
+	avgChange, avgRegressed := compareChange(float64(b.LatencyAvg), float64(c.LatencyAvg), params.Tolerance, true)
+	p95Change, p95Regressed := compareChange(float64(b.LatencyP95), float64(c.LatencyP95), params.Tolerance, true)
+	dropChange := c.DropPercent - b.DropPercent
+
+	return []*comparedMetric{
+		metric("bitrate", formatBps(b.Bitrate), formatBps(c.Bitrate), bitrateChange, bitrateRegressed),
+		metric("latency avg", formatLatency(b.LatencyAvg), formatLatency(c.LatencyAvg), avgChange, avgRegressed),
+		metric("latency p95", formatLatency(b.LatencyP95), formatLatency(c.LatencyP95), p95Change, p95Regressed),
+		metric("dropped", fmt.Sprintf("%.3f%%", b.DropPercent), fmt.Sprintf("%.3f%%", c.DropPercent),
+			fmt.Sprintf("%+.3f pts", dropChange), dropChange > params.DropTolerance),
+		metric("errors", fmt.Sprint(b.Errors), fmt.Sprint(c.Errors), fmt.Sprintf("%+d", c.Errors-b.Errors),
+			c.Errors > b.Errors),
+	}
+}
+
+// compareChange formats the change from baseline to candidate in percent and tells whether it is
+// worse than tolerance, higher values being worse when higherIsWorse. A zero baseline has no
+// relative change, so "n/a" is shown and any change for the worse is a regression.
+func compareChange(baseline, candidate, tolerance float64, higherIsWorse bool) (string, bool) {
+	if baseline == 0 {
+		if candidate == 0 {
+			return formatChange(0), false
+		}
+		return "n/a", (candidate > 0) == higherIsWorse
+	}
+
+	change := (candidate - baseline) / baseline * 100
+	worse := change
+	if !higherIsWorse {
+		worse = -change
+	}
+	return formatChange(change), worse > tolerance
+}
+
+func formatChange(change float64) string {
+	return fmt.Sprintf("%+.1f%%", change)
+}
+
+func formatBps(bps float64) string {
+	return formatBitrate(int64(bps/8), time.Second)
+}
+
+func printComparison(out io.Writer, metrics []*comparedMetric) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nComparison\t| Room\t| Kind\t| Metric\t| Baseline\t| Candidate\t| Delta\t| Result\n")
+	for _, m := range metrics {
+		result := "ok"
+		if m.regressed {
+			result = "REGRESSED"
+		}
+		_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\n",
+			m.room, m.kind, m.name, m.baseline, m.candidate, m.delta, result)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,102 @@
+package loadtester
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareExports(t *testing.T) {
+	export := func(bitrate float64, latency time.Duration, drops float64) *Export {
+		return &Export{Rooms: []*RoomExport{{
+			Room: "room",
+			Totals: []*SummaryExport{{
+				Kind:        TrackKindVideo,
+				Bitrate:     bitrate,
+				LatencyAvg:  latency,
+				LatencyP95:  latency,
+				DropPercent: drops,
+			}},
+		}}}
+	}
+	params := CompareParams{Tolerance: 5, DropTolerance: 0.5}
+
+	metrics, unmatched := compareExports(export(1e6, 100*time.Millisecond, 1), export(0.97e6, 104*time.Millisecond, 1.2), params)
+	require.Empty(t, unmatched)
+	require.Len(t, metrics, 5)
+	for _, m := range metrics {
+		require.False(t, m.regressed, m.name)
+	}
+
+	metrics, _ = compareExports(export(1e6, 100*time.Millisecond, 1), export(0.9e6, 120*time.Millisecond, 2), params)
+	regressed := map[string]bool{}
+	for _, m := range metrics {
+		regressed[m.name] = m.regressed
+	}
+	require.Equal(t, map[string]bool{
+		"bitrate":     true,
+		"latency avg": true,
+		"latency p95": true,
+		"dropped":     true,
+		"errors":      false,
+	}, regressed)
+
+	// a zero baseline has no relative change, worse candidates still regress
+	metrics, _ = compareExports(export(0, 0, 1), export(1e6, 10*time.Millisecond, 1), params)
+	regressed = map[string]bool{}
+	for _, m := range metrics {
+		regressed[m.name] = m.regressed
+		if m.name != "dropped" && m.name != "errors" {
+			require.Equal(t, "n/a", m.delta, m.name)
+		}
+	}
+	require.Equal(t, map[string]bool{
+		"bitrate":     false,
+		"latency avg": true,
+		"latency p95": true,
+		"dropped":     false,
+		"errors":      false,
+	}, regressed)
+
+	// the baseline's room is missing from the candidate, which has another one
+	other := export(1e6, 100*time.Millisecond, 1)
+	other.Rooms[0].Room = "other"
+	metrics, unmatched = compareExports(export(1e6, 100*time.Millisecond, 1), other, params)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "room", metrics[0].room)
+	require.True(t, metrics[0].regressed)
+	require.Equal(t, []string{"Room other is only in the candidate"}, unmatched)
+
+	// the candidate has no audio, and audio it didn't have before is not a regression
+	withAudio := export(1e6, 100*time.Millisecond, 1)
+	withAudio.Rooms[0].Totals = append(withAudio.Rooms[0].Totals, &SummaryExport{Kind: TrackKindAudio})
+	metrics, unmatched = compareExports(withAudio, export(1e6, 100*time.Millisecond, 1), params)
+	require.Empty(t, unmatched)
+	require.Len(t, metrics, 6)
+	require.Equal(t, TrackKindAudio, metrics[5].kind)
+	require.True(t, metrics[5].regressed)
+	metrics, _ = compareExports(export(1e6, 100*time.Millisecond, 1), withAudio, params)
+	require.Len(t, metrics, 5)
+	for _, m := range metrics {
+		require.False(t, m.regressed, m.name)
+	}
+
+	dir := t.TempDir()
+	write := func(name string, e *Export) string {
+		path := filepath.Join(dir, name)
+		content, err := json.Marshal(e)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, content, 0644))
+		return path
+	}
+	err := CompareResults(
+		write("baseline.json", export(1e6, 100*time.Millisecond, 1)),
+		write("candidate.json", export(0.5e6, 100*time.Millisecond, 1)),
+		params,
+	)
+	require.Equal(t, &RegressionError{Regressions: 1}, err)
+}
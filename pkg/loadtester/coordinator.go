@@ -0,0 +1,435 @@
+package loadtester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-cli/pkg/provider"
+)
+
+const (
+	coordinatorRegisterPath   = "/register"
+	coordinatorAssignmentPath = "/assignment"
+	coordinatorResultsPath    = "/results"
+
+	// time given to agents between receiving their assignment and starting the test
+	agentStartDelay = 3 * time.Second
+)
+
+type CoordinatorParams struct {
+	// address to listen on for agents, e.g. :7801
+	Address string
+	// number of agents to wait for before starting
+	Agents int
+	// the whole test, split between the agents
+	Params Params
+	// tables and progress are printed here, stdout when nil
+	Out io.Writer
+}
+
+// Coordinator splits one test between agents that register with it over HTTP,
+// starts them together and merges their results into one report
+type Coordinator struct {
+	params      CoordinatorParams
+	assignments []Params
+
+	lock     sync.Mutex
+	startAt  time.Time
+	agents   []string
+	results  map[int]*AgentResult
+	ready    chan struct{}
+	finished chan struct{}
+}
+
+type registerRequest struct {
+	Name string `json:"name"`
+}
+
+type registerResponse struct {
+	ID int `json:"id"`
+}
+
+// AgentAssignment is the part of the test an agent has to run
+type AgentAssignment struct {
+	Params Params `json:"params"`
+	// time until the common start, relative so that agents whose clocks are off still start together
+	StartIn time.Duration `json:"start_in_ns"`
+}
+
+// AgentResult is what an agent reports back once its part of the test is done
+type AgentResult struct {
+	Agent   string          `json:"agent"`
+	Error   string          `json:"error,omitempty"`
+	Testers []*TesterResult `json:"testers"`
+	Phases  []*PhaseResult  `json:"phases,omitempty"`
+	// publishers, with their join milestones and what they sent rather than tracks
+	Publishers []*TesterResult `json:"publishers,omitempty"`
+	// offset from the reference clock the agent measured latencies with
+	Clock *ClockOffset `json:"clock,omitempty"`
+}
+
+func NewCoordinator(params CoordinatorParams) (*Coordinator, error) {
+	if params.Agents <= 0 {
+		return nil, errors.New("at least one agent is required")
+	}
+	if params.Out == nil {
+		params.Out = os.Stdout
+	}
+
+	if params.Params.Duration == 0 && len(params.Params.Phases) == 0 {
+		return nil, errors.New("coordinated tests need a duration or phases")
+	}
+
+	// resolution names are resolved here, agents need the same manifest
+	if params.Params.MediaDir != "" {
+		if err := provider.UseMediaDir(params.Params.MediaDir); err != nil {
+			return nil, err
+		}
+	}
+
+	assignments, err := shardParams(NewLoadTest(params.Params).Params, params.Agents)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Coordinator{
+		params:      params,
+		assignments: assignments,
+		results:     make(map[int]*AgentResult),
+		ready:       make(chan struct{}),
+		finished:    make(chan struct{}),
+	}, nil
+}
+
+// Run serves agents until all of them reported results, or ctx is canceled, then prints the merged report
+func (c *Coordinator) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", c.params.Address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(coordinatorRegisterPath, c.handleRegister)
+	mux.HandleFunc(coordinatorAssignmentPath, c.handleAssignment)
+	mux.HandleFunc(coordinatorResultsPath, c.handleResults)
+	mux.HandleFunc(coordinatorTimePath, handleTime)
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	fmt.Fprintf(c.params.Out, "Coordinator listening on %s, waiting for %d agents\n", listener.Addr(), c.params.Agents)
+
+	select {
+	case <-ctx.Done():
+	case <-c.finished:
+	}
+
+	return c.printResults()
+}
+
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &registerRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.lock.Lock()
+	if len(c.agents) >= c.params.Agents {
+		c.lock.Unlock()
+		http.Error(w, "all agents are already registered", http.StatusConflict)
+		return
+	}
+	id := len(c.agents)
+	c.agents = append(c.agents, req.Name)
+	if len(c.agents) == c.params.Agents {
+		close(c.ready)
+	}
+	c.lock.Unlock()
+
+	fmt.Fprintf(c.params.Out, "Agent %d registered: %s\n", id, req.Name)
+	writeJSON(w, &registerResponse{ID: id})
+}
+
+func (c *Coordinator) handleAssignment(w http.ResponseWriter, r *http.Request) {
+	id, ok := c.agentID(w, r)
+	if !ok {
+		return
+	}
+
+	// hold the request until every agent is registered, so that they start together
+	select {
+	case <-c.ready:
+	case <-r.Context().Done():
+		return
+	}
+
+	startIn := time.Until(c.agentsStartAt())
+	if startIn < 0 {
+		startIn = 0
+	}
+	writeJSON(w, &AgentAssignment{
+		Params:  c.assignments[id],
+		StartIn: startIn,
+	})
+}
+
+func (c *Coordinator) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := c.agentID(w, r)
+	if !ok {
+		return
+	}
+
+	res := &AgentResult{}
+	if err := json.NewDecoder(r.Body).Decode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.lock.Lock()
+	if _, reported := c.results[id]; reported {
+		c.lock.Unlock()
+		http.Error(w, "agent already reported results", http.StatusConflict)
+		return
+	}
+	c.results[id] = res
+	done := len(c.results) == c.params.Agents
+	c.lock.Unlock()
+
+	fmt.Fprintf(c.params.Out, "Received results from agent %d: %s\n", id, res.Agent)
+	w.WriteHeader(http.StatusNoContent)
+
+	if done {
+		close(c.finished)
+	}
+}
+
+func (c *Coordinator) agentID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get("agent"))
+	if err != nil || id < 0 || id >= c.params.Agents {
+		http.Error(w, "invalid agent", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// agentsStartAt is the same for every agent, fixed when the first assignment goes out
+func (c *Coordinator) agentsStartAt() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.startAt.IsZero() {
+		c.startAt = time.Now().Add(agentStartDelay)
+	}
+
+	return c.startAt
+}
+
+func (c *Coordinator) printResults() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.results) < c.params.Agents {
+		fmt.Fprintf(c.params.Out, "\nOnly %d of %d agents reported results\n", len(c.results), c.params.Agents)
+	}
+
+	stats := make(map[string]map[string]*testerStats)
+	publishers := make(map[string]map[string]*testerStats)
+	var phases []*phaseStats
+	var clocks []*ClockOffset
+	failed := 0
+	for id := 0; id < c.params.Agents; id++ {
+		res := c.results[id]
+		if res == nil {
+			continue
+		}
+
+		if res.Error != "" {
+			fmt.Fprintf(c.params.Out, "Agent %d (%s) failed: %s\n", id, res.Agent, res.Error)
+			failed++
+		}
+
+		label := fmt.Sprintf("agent %d", id)
+		addTesterResults(stats, res.Testers, label)
+		addTesterResults(publishers, res.Publishers, label)
+		if res.Clock != nil {
+			clock := *res.Clock
+			clock.Agent = fmt.Sprintf("%d (%s)", id, res.Agent)
+			clocks = append(clocks, &clock)
+		}
+
+		for i, p := range res.Phases {
+			if i == len(phases) {
+				phases = append(phases, &phaseStats{
+					name:        p.Name,
+					duration:    p.Duration,
+					subscribers: p.Subscribers,
+					stats:       make(map[string]map[string]*testerStats),
+				})
+			}
+			addTesterResults(phases[i].stats, p.Testers, label)
+		}
+	}
+
+	if failed > 0 && failed == len(c.results) {
+		return fmt.Errorf("all %d agents that reported results failed", failed)
+	}
+	if len(stats) == 0 && len(publishers) == 0 {
+		return errors.New("no agent reported results")
+	}
+
+	t := NewLoadTest(c.params.Params)
+	t.Out = c.params.Out
+	if len(stats) > 0 {
+		t.printStats(stats)
+	}
+	t.printJoins(stats, publishers)
+	t.printPublishers(publishers)
+	if len(phases) > 0 {
+		t.printPhases(phases)
+	}
+	printClocks(t.Out, clocks, stats)
+
+	e := newExport(t.Params, stats, phases, c.startAt, time.Now())
+	e.addPublishers(publishers)
+	e.Metadata.Clocks = clocks
+	if err := writeExport(t.Out, e, t.Params.Output, t.Params.OutputFile); err != nil {
+		return err
+	}
+
+	if len(stats) == 0 {
+		// publisher-only agents, thresholds are checked against the publishers' errors
+		return t.checkResults(publishers)
+	}
+
+	return t.checkResults(stats)
+}
+
+// shardParams splits publishers (or remote rooms) into contiguous ranges, one per agent.
+// When all participants share one room, subscribers are split between agents as well.
+func shardParams(params Params, agents int) ([]Params, error) {
+	isRemote := params.RemotePublishers > 0
+	start, end := params.StartPublisher, params.EndPublisher
+	if isRemote {
+		start, end = params.StartRemoteRoomNumber, params.EndRemoteRoomNumber
+	} else if start == 0 {
+		start, end = 1, params.VideoPublishers
+	}
+
+	if end-start+1 < agents {
+		return nil, fmt.Errorf("cannot split %d publishers between %d agents", end-start+1, agents)
+	}
+
+	prefix := params.IdentityPrefix
+	if prefix == "" {
+		prefix = randStringRunes(5)
+	}
+
+	var shards []Params
+	for i := 0; i < agents; i++ {
+		p := params
+		s, e := splitRange(start, end, agents, i)
+		if isRemote {
+			p.StartRemoteRoomNumber, p.EndRemoteRoomNumber = s, e
+		} else {
+			p.StartPublisher, p.EndPublisher = s, e
+		}
+
+		// churn rates are across all rooms, agents take the share of the testers they run
+		p.Churn.PublisherRate = splitRate(params.Churn.PublisherRate, e-s+1, end-start+1)
+		p.Churn.SubscriberRate = splitRate(params.Churn.SubscriberRate, e-s+1, end-start+1)
+
+		if p.SameRoom {
+			p.Subscribers = splitCount(params.Subscribers, agents, i)
+			p.Churn.SubscriberRate = splitRate(params.Churn.SubscriberRate, p.Subscribers, params.Subscribers)
+			p.HighQualityViewer = splitCount(params.HighQualityViewer, agents, i)
+			p.MediumQualityView = splitCount(params.MediumQualityView, agents, i)
+			p.LowQualityViewer = splitCount(params.LowQualityViewer, agents, i)
+			p.DataPublishers = splitCount(params.DataPublishers, agents, i)
+
+			p.Phases = make([]Phase, len(params.Phases))
+			for j, phase := range params.Phases {
+				phase.Subscribers = splitCount(phase.Subscribers, agents, i)
+				p.Phases[j] = phase
+			}
+		}
+
+		// only the resolutions of this agent's publishers
+		resolutions := NewLoadTest(params).GetResolutions(isRemote)
+		p.VideoResolution = strings.Join(resolutions[s-start:e-start+1], " ")
+		if !isRemote {
+			// publishers keep the codec they have in the whole test
+			codecs := NewLoadTest(params).GetCodecs()
+			p.VideoCodec = strings.Join(codecs[s-start:e-start+1], " ")
+		}
+		p.IdentityPrefix = fmt.Sprintf("%s_a%d", prefix, i)
+		p.URL, p.APIKey, p.APISecret = "", "", ""
+		// results are checked and reported by the coordinator
+		p.JUnitFile = ""
+		p.Output, p.OutputFile = OutputNone, ""
+		// agents choose their own metrics address
+		p.MetricsAddr = ""
+
+		shards = append(shards, p)
+	}
+
+	return shards, nil
+}
+
+// splitRange returns the i-th of n contiguous parts of [start, end], earlier parts take the remainder
+func splitRange(start, end, n, i int) (int, int) {
+	total := end - start + 1
+	s := start + i*(total/n)
+	if i < total%n {
+		s += i
+	} else {
+		s += total % n
+	}
+	return s, s + splitCount(total, n, i) - 1
+}
+
+// splitCount returns the size of the i-th of n parts of total, earlier parts take the remainder
+func splitCount(total, n, i int) int {
+	c := total / n
+	if i < total%n {
+		c++
+	}
+	return c
+}
+
+// splitRate returns the part of rate of part out of total testers
+func splitRate(rate float64, part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return rate * float64(part) / float64(total)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
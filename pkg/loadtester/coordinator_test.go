@@ -0,0 +1,115 @@
+package loadtester
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardParams(t *testing.T) {
+	params := NewLoadTest(Params{
+		StartPublisher:  1,
+		EndPublisher:    5,
+		Subscribers:     3,
+		VideoResolution: "1080p 720p 360p",
+		VideoCodec:      "vp8 h264",
+		Duration:        time.Minute,
+		Churn:           ChurnParams{SubscriberRate: 10, PublisherRate: 5},
+		TesterParams: TesterParams{
+			IdentityPrefix: "lt",
+		},
+	}).Params
+
+	shards, err := shardParams(params, 2)
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+
+	require.Equal(t, 1, shards[0].StartPublisher)
+	require.Equal(t, 3, shards[0].EndPublisher)
+	require.Equal(t, "1080p 720p 360p", shards[0].VideoResolution)
+	require.Equal(t, "vp8 h264 vp8", shards[0].VideoCodec)
+	require.Equal(t, "lt_a0", shards[0].IdentityPrefix)
+
+	require.Equal(t, 4, shards[1].StartPublisher)
+	require.Equal(t, 5, shards[1].EndPublisher)
+	require.Equal(t, "1080p 1080p", shards[1].VideoResolution)
+	require.Equal(t, "h264 vp8", shards[1].VideoCodec)
+	require.Equal(t, "lt_a1", shards[1].IdentityPrefix)
+
+	// subscribers are per room, so they are not split
+	require.Equal(t, 3, shards[0].Subscribers)
+	require.Equal(t, 3, shards[1].Subscribers)
+
+	// churn rates follow the share of publishers, which subscribers are per
+	require.InDelta(t, 3, shards[0].Churn.PublisherRate, 0.001)
+	require.InDelta(t, 2, shards[1].Churn.PublisherRate, 0.001)
+	require.InDelta(t, 6, shards[0].Churn.SubscriberRate, 0.001)
+	require.InDelta(t, 4, shards[1].Churn.SubscriberRate, 0.001)
+
+	_, err = shardParams(params, 6)
+	require.Error(t, err)
+}
+
+func TestShardParamsSameRoom(t *testing.T) {
+	params := NewLoadTest(Params{
+		VideoPublishers:   3,
+		Subscribers:       5,
+		HighQualityViewer: 3,
+		SameRoom:          true,
+		Duration:          time.Minute,
+		Churn:             ChurnParams{SubscriberRate: 10},
+	}).Params
+
+	shards, err := shardParams(params, 3)
+	require.NoError(t, err)
+
+	subscribers, high := 0, 0
+	rate := 0.0
+	for i, s := range shards {
+		require.Equal(t, i+1, s.StartPublisher)
+		require.Equal(t, i+1, s.EndPublisher)
+		subscribers += s.Subscribers
+		high += s.HighQualityViewer
+		rate += s.Churn.SubscriberRate
+	}
+	require.Equal(t, 5, subscribers)
+	require.Equal(t, 3, high)
+	// agents churn subscribers in proportion to the ones they run
+	require.InDelta(t, 10, rate, 0.001)
+	require.InDelta(t, 4, shards[0].Churn.SubscriberRate, 0.001)
+}
+
+func TestCoordinatorResults(t *testing.T) {
+	c, err := NewCoordinator(CoordinatorParams{
+		Agents: 2,
+		Params: Params{VideoPublishers: 2, Duration: time.Minute},
+	})
+	require.NoError(t, err)
+
+	post := func(agent, body string) int {
+		w := httptest.NewRecorder()
+		c.handleResults(w, httptest.NewRequest(http.MethodPost, coordinatorResultsPath+"?agent="+agent, strings.NewReader(body)))
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusNoContent, post("0", `{"agent": "a", "error": "could not connect"}`))
+	// a retried report doesn't replace the first one
+	require.Equal(t, http.StatusConflict, post("0", `{"agent": "a"}`))
+	require.Equal(t, http.StatusBadRequest, post("2", `{"agent": "c"}`))
+	require.Equal(t, http.StatusNoContent, post("1", `{"agent": "b", "error": "could not connect"}`))
+
+	select {
+	case <-c.finished:
+	default:
+		t.Fatal("coordinator did not finish")
+	}
+	// reports after the test finished are rejected rather than closing finished again
+	require.Equal(t, http.StatusConflict, post("1", `{"agent": "b"}`))
+
+	// every agent failed
+	require.Error(t, c.printResults())
+}
@@ -0,0 +1,193 @@
+package loadtester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"golang.org/x/sync/syncmap"
+)
+
+const dashboardInterval = time.Second
+
+// dashboard redraws per room figures while the test is running. Bitrate and latency are
+// over the last refresh, drops, reconnects and errors since the start of the test.
+type dashboard struct {
+	out io.Writer
+	// redraw in place rather than appending a new table every refresh
+	terminal  bool
+	errs      *syncmap.Map
+	startedAt time.Time
+
+	fuse core.Fuse
+	wg   sync.WaitGroup
+
+	// counters of the previous refresh, to take rates from
+	prev      map[*LoadTester]map[string]*trackStats
+	refreshed time.Time
+	lines     int
+}
+
+type dashboardRoom struct {
+	name         string
+	connected    int
+	bytes        int64
+	latency      int64
+	latencyCount int64
+	packets      int64
+	dropped      int64
+	reconnects   int64
+	errors       int
+}
+
+func newDashboard(out io.Writer, errs *syncmap.Map) *dashboard {
+	d := &dashboard{
+		out:       out,
+		errs:      errs,
+		startedAt: time.Now(),
+		prev:      make(map[*LoadTester]map[string]*trackStats),
+	}
+	if f, ok := out.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			d.terminal = true
+		}
+	}
+
+	return d
+}
+
+// Start refreshes the dashboard from its own goroutine until Stop, testers is called on every refresh
+func (d *dashboard) Start(testers func() []*LoadTester) {
+	if d.fuse != nil {
+		return
+	}
+	d.fuse = core.NewFuse()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(dashboardInterval)
+		defer ticker.Stop()
+
+		d.refresh(time.Now(), testers())
+		for {
+			select {
+			case <-d.fuse.Watch():
+				return
+			case now := <-ticker.C:
+				d.refresh(now, testers())
+			}
+		}
+	}()
+}
+
+func (d *dashboard) Stop() {
+	if d.fuse == nil || d.fuse.IsBroken() {
+		return
+	}
+	d.fuse.Break()
+	d.wg.Wait()
+}
+
+// refresh redraws the dashboard, at most once per dashboardInterval
+func (d *dashboard) refresh(now time.Time, testers []*LoadTester) {
+	if !d.refreshed.IsZero() && now.Sub(d.refreshed) < dashboardInterval-dashboardInterval/10 {
+		return
+	}
+
+	var elapsed time.Duration
+	if !d.refreshed.IsZero() {
+		elapsed = now.Sub(d.refreshed)
+	}
+	d.refreshed = now
+
+	rooms := make(map[string]*dashboardRoom)
+	prev := make(map[*LoadTester]map[string]*trackStats, len(testers))
+	for _, t := range testers {
+		room := rooms[t.params.Room]
+		if room == nil {
+			room = &dashboardRoom{name: t.params.Room}
+			rooms[t.params.Room] = room
+		}
+
+		if t.IsConnected() {
+			room.connected++
+		}
+		room.reconnects += int64(t.reconnects.Load())
+		if e, _ := d.errs.Load(t.params.name); e != nil {
+			room.errors++
+		}
+
+		cur := t.getStats().stats
+		for id, s := range cur {
+			room.packets += s.packets.Load()
+			room.dropped += s.dropped.Load()
+
+			// testers that showed up since the last refresh have no rate yet
+			last, ok := d.prev[t][id]
+			if !ok {
+				continue
+			}
+			if !last.startedAt.Load().Equal(s.startedAt.Load()) {
+				// reset since the last refresh, e.g. at the end of the warm-up, everything is new
+				last = &trackStats{}
+			}
+			room.bytes += s.bytes.Load() - last.bytes.Load()
+			room.latency += s.latency.Load() - last.latency.Load()
+			room.latencyCount += s.latencyCount.Load() - last.latencyCount.Load()
+		}
+		prev[t] = cur
+	}
+	d.prev = prev
+
+	d.draw(now, elapsed, rooms)
+}
+
+// keep leaves the last table on screen, the next refresh draws below whatever is printed until then
+func (d *dashboard) keep() {
+	d.lines = 0
+}
+
+func (d *dashboard) draw(now time.Time, elapsed time.Duration, rooms map[string]*dashboardRoom) {
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintf(w, "Live stats, %s elapsed\n", now.Sub(d.startedAt).Round(time.Second))
+	_, _ = fmt.Fprint(w, "Room\t| Connected\t| Bitrate\t| Latency\t| Total Dropped\t| Reconnects\t| Errors\n")
+	for _, name := range names {
+		r := rooms[name]
+		latency := " - "
+		if r.latencyCount > 0 {
+			latency = formatLatency(time.Duration(r.latency / r.latencyCount))
+		}
+		_, sDropped := formatStrings(r.packets, 0, 0, r.dropped)
+		_, _ = fmt.Fprintf(w, "%s\t| %d\t| %s\t| %s\t| %s\t| %d\t| %d\n",
+			name, r.connected, formatBitrate(r.bytes, elapsed), latency, sDropped, r.reconnects, r.errors)
+	}
+	_ = w.Flush()
+
+	if d.terminal {
+		// back to the first line of the previous table, clearing it and whatever was printed after it
+		if d.lines > 0 {
+			_, _ = fmt.Fprintf(d.out, "\r\033[%dA\033[J", d.lines)
+		} else {
+			_, _ = fmt.Fprint(d.out, "\r\033[K")
+		}
+		d.lines = bytes.Count(buf.Bytes(), []byte("\n"))
+	} else {
+		_, _ = fmt.Fprintln(d.out)
+	}
+	_, _ = d.out.Write(buf.Bytes())
+}
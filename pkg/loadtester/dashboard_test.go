@@ -0,0 +1,66 @@
+package loadtester
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/syncmap"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestDashboard(t *testing.T) {
+	errs := &syncmap.Map{}
+	sub := NewLoadTester(TesterParams{Room: "room", name: "Sub 0 in room"}, livekit.VideoQuality_HIGH)
+	failed := NewLoadTester(TesterParams{Room: "room", name: "Sub 1 in room"}, livekit.VideoQuality_HIGH)
+	errs.Store("Sub 1 in room", errors.New("could not connect"))
+
+	s := &trackStats{trackID: "TR_video", kind: TrackKindVideo}
+	s.startedAt.Store(time.Now())
+	sub.stats.Store(s.trackID, s)
+	sub.reconnects.Inc()
+
+	var out bytes.Buffer
+	d := newDashboard(&out, errs)
+
+	now := time.Now()
+	d.refresh(now, []*LoadTester{sub, failed})
+	require.Contains(t, out.String(), "room | 0         |  -      |  -      |  -            | 1          | 1")
+
+	// 125kB and 100 packets of 20ms in the next second
+	s.bytes.Add(125000)
+	s.packets.Add(100)
+	s.dropped.Add(1)
+	for i := 0; i < 100; i++ {
+		s.recordLatency(int64(20 * time.Millisecond))
+	}
+
+	out.Reset()
+	d.refresh(now.Add(time.Second/2), []*LoadTester{sub, failed})
+	require.Empty(t, out.String(), "refreshed too early")
+
+	d.refresh(now.Add(time.Second), []*LoadTester{sub, failed})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[2], "1.0mbps")
+	require.Contains(t, lines[2], "20ms")
+	require.Contains(t, lines[2], "1 (0.99")
+
+	// the warm-up ends, stats are reset and only what came since counts
+	sub.Reset()
+	s.bytes.Add(62500)
+	for i := 0; i < 50; i++ {
+		s.recordLatency(int64(40 * time.Millisecond))
+	}
+
+	out.Reset()
+	d.refresh(now.Add(2*time.Second), []*LoadTester{sub, failed})
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[2], "500.0kbps")
+	require.Contains(t, lines[2], "40ms")
+}
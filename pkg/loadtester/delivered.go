@@ -0,0 +1,472 @@
+package loadtester
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// deliveredQualities follows which simulcast layer a subscriber receives. The SFU rewrites SSRCs and sends
+// no RID, but it only switches layers on keyframes, and keyframes tell their dimensions.
+type deliveredQualities struct {
+	lock    sync.Mutex
+	current string
+	since   time.Time
+	// time on each quality, not counting the current one since it was switched to
+	time     map[string]time.Duration
+	switches int64
+}
+
+// frameDimensions reads width and height from a keyframe: the SPS of H.264 and the frame header of VP8.
+// It returns false for other frames and codecs.
+func frameDimensions(mimeType string, packets []*rtp.Packet) (width, height int, ok bool) {
+	for _, pkt := range packets {
+		payload := pkt.Payload
+		if len(payload) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(mimeType) {
+		case strings.ToLower(webrtc.MimeTypeH264):
+			if sps := h264SPS(payload); sps != nil {
+				return h264SPSDimensions(sps)
+			}
+
+		case strings.ToLower(webrtc.MimeTypeVP8):
+			p := &codecs.VP8Packet{}
+			frame, err := p.Unmarshal(payload)
+			if err != nil || p.S != 1 || p.PID != 0 {
+				continue
+			}
+			// keyframe tag, start code and 14 bit dimensions
+			if len(frame) < 10 || frame[0]&0x01 != 0 || frame[3] != 0x9d || frame[4] != 0x01 || frame[5] != 0x2a {
+				return 0, 0, false
+			}
+			return int(binary.LittleEndian.Uint16(frame[6:]) & 0x3fff), int(binary.LittleEndian.Uint16(frame[8:]) & 0x3fff), true
+
+		default:
+			return 0, 0, false
+		}
+	}
+
+	return 0, 0, false
+}
+
+const h264NALTypeSPS = 7
+
+// h264SPS returns the SPS of a packet sent on its own or in a STAP-A, nil when it has none
+func h264SPS(payload []byte) []byte {
+	switch payload[0] & 0x1f {
+	case h264NALTypeSPS:
+		return payload
+
+	case h264NALTypeSTAPA:
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			if i+2+size > len(payload) {
+				return nil
+			}
+			if payload[i+2]&0x1f == h264NALTypeSPS {
+				return payload[i+2 : i+2+size]
+			}
+			i += 2 + size
+		}
+	}
+
+	return nil
+}
+
+// h264SPSDimensions parses the picture size of an SPS NAL unit (ITU-T H.264 7.3.2.1.1), cropping applied
+func h264SPSDimensions(nal []byte) (width, height int, ok bool) {
+	if len(nal) < 4 {
+		return 0, 0, false
+	}
+	r := &bitReader{data: unescapeRBSP(nal[1:])}
+
+	profile := r.bits(8)
+	r.bits(16) // constraint flags and level
+	r.ue()     // seq_parameter_set_id
+	chromaFormat := 1
+	separateColourPlanes := false
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			separateColourPlanes = r.bits(1) == 1
+		}
+		r.ue()    // bit_depth_luma_minus8
+		r.ue()    // bit_depth_chroma_minus8
+		r.bits(1) // qpprime_y_zero_transform_bypass_flag
+		// seq_scaling_matrix_present_flag, the scaling lists are skipped
+		if r.bits(1) == 1 {
+			lists := 8
+			if chromaFormat == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if r.bits(1) == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				last, next := 8, 8
+				for j := 0; j < size && next != 0; j++ {
+					next = (last + r.se() + 256) % 256
+					if next != 0 {
+						last = next
+					}
+				}
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+	// pic_order_cnt_type
+	switch r.ue() {
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.bits(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		for i := r.ue(); i > 0 && r.err == nil; i-- {
+			r.se()
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.bits(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthInMbs := r.ue() + 1
+	heightInMapUnits := r.ue() + 1
+	frameMbsOnly := r.bits(1)
+	if frameMbsOnly == 0 {
+		r.bits(1) // mb_adaptive_frame_field_flag
+	}
+	r.bits(1) // direct_8x8_inference_flag
+
+	width = widthInMbs * 16
+	height = (2 - frameMbsOnly) * heightInMapUnits * 16
+	// frame_cropping_flag
+	if r.bits(1) == 1 {
+		left, right, top, bottom := r.ue(), r.ue(), r.ue(), r.ue()
+		cropX, cropY := 1, 2-frameMbsOnly
+		if chromaFormat != 0 && !separateColourPlanes {
+			if chromaFormat == 1 || chromaFormat == 2 {
+				cropX = 2
+			}
+			if chromaFormat == 1 {
+				cropY *= 2
+			}
+		}
+		width -= cropX * (left + right)
+		height -= cropY * (top + bottom)
+	}
+
+	if r.err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+var errBitsExhausted = errors.New("not enough bits")
+
+// bitReader reads the fixed and Exp-Golomb coded fields of an RBSP, reading past its end sets err
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *bitReader) bits(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.err = errBitsExhausted
+			return 0
+		}
+		v = v<<1 | int(r.data[r.pos/8]>>(7-r.pos%8))&0x01
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() int {
+	zeros := 0
+	for r.bits(1) == 0 {
+		if r.err != nil || zeros > 31 {
+			r.err = errBitsExhausted
+			return 0
+		}
+		zeros++
+	}
+	return 1<<zeros - 1 + r.bits(zeros)
+}
+
+func (r *bitReader) se() int {
+	v := r.ue()
+	if v%2 == 0 {
+		return -v / 2
+	}
+	return (v + 1) / 2
+}
+
+// qualityForDimensions finds the simulcast layer of a frame, the one closest in size.
+// It returns false when the track has no simulcast or layers of several qualities are as close,
+// e.g. for ladders that send every layer at the same size.
+func qualityForDimensions(layers []*livekit.VideoLayer, width, height int) (string, bool) {
+	if len(layers) < 2 {
+		return "", false
+	}
+
+	var quality livekit.VideoQuality
+	best, ties := -1, 0
+	for _, l := range layers {
+		d := abs(int(l.Width)-width) + abs(int(l.Height)-height)
+		switch {
+		case best < 0 || d < best:
+			best, ties, quality = d, 0, l.Quality
+		case d == best && l.Quality != quality:
+			ties++
+		}
+	}
+	if ties > 0 {
+		return "", false
+	}
+	return strings.ToLower(quality.String()), true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// recordDelivered notes the quality of a keyframe that arrived at now
+func (s *trackStats) recordDelivered(quality string, now, subscribedAt time.Time) {
+	if quality == s.requested && s.timeToRequested.Load() == 0 {
+		s.timeToRequested.Store(now.Sub(subscribedAt))
+	}
+
+	d := &s.delivered
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if quality == d.current {
+		return
+	}
+	if d.current != "" {
+		if d.time == nil {
+			d.time = make(map[string]time.Duration)
+		}
+		d.time[d.current] += now.Sub(d.since)
+		d.switches++
+	}
+	d.current = quality
+	d.since = now
+}
+
+// deliveredTimes returns the time spent on each quality up to until, and the number of layer switches
+func (s *trackStats) deliveredTimes(until time.Time) (map[string]time.Duration, int64) {
+	d := &s.delivered
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	times := make(map[string]time.Duration, len(d.time)+1)
+	for q, t := range d.time {
+		times[q] = t
+	}
+	if d.current != "" && until.After(d.since) {
+		times[d.current] += until.Sub(d.since)
+	}
+	if len(times) == 0 {
+		return nil, d.switches
+	}
+	return times, d.switches
+}
+
+// setDelivered restores times and switches of a snapshot or result, they no longer change
+func (s *trackStats) setDelivered(times map[string]time.Duration, switches int64) {
+	d := &s.delivered
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.current = ""
+	d.time = times
+	d.switches = switches
+}
+
+// resetDelivered starts counting anew from the quality being received
+func (s *trackStats) resetDelivered() {
+	d := &s.delivered
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.time = nil
+	d.switches = 0
+	if d.current != "" {
+		d.since = time.Now()
+	}
+}
+
+// subtractDelivered returns the time per quality of current that is not in prev
+func subtractDelivered(current, prev map[string]time.Duration) map[string]time.Duration {
+	var times map[string]time.Duration
+	for q, t := range current {
+		if t -= prev[q]; t > 0 {
+			if times == nil {
+				times = make(map[string]time.Duration)
+			}
+			times[q] = t
+		}
+	}
+	return times
+}
+
+// mainQuality is the quality received for the longest time, empty when none was recognized
+func mainQuality(times map[string]time.Duration) string {
+	main := ""
+	for q, t := range times {
+		if main == "" || t > times[main] || t == times[main] && qualityOrder[q] < qualityOrder[main] {
+			main = q
+		}
+	}
+	return main
+}
+
+// deliveredSummary adds up the qualities subscribers of a room received for one requested quality
+type deliveredSummary struct {
+	tracks int
+	// tracks that received the requested quality for most of the time
+	matched  int
+	time     map[string]time.Duration
+	switches int64
+	// time to the requested quality over reachedTracks tracks that got there
+	toRequested    time.Duration
+	reachedTracks  int
+	maxToRequested time.Duration
+}
+
+// printDelivered shows per room and requested quality which simulcast layers subscribers received,
+// how long it took to get the requested one and how often the SFU switched layers.
+// Nothing is printed unless some track received keyframes of a recognized layer.
+func (t *LoadTest) printDelivered(stats map[string]map[string]*testerStats) {
+	type deliveredKey struct {
+		room, requested string
+	}
+	summaries := make(map[deliveredKey]*deliveredSummary)
+	for room, roomStats := range stats {
+		for _, ts := range roomStats {
+			for _, s := range ts.stats {
+				times, switches := s.deliveredTimes(s.endedAt.Load())
+				if len(times) == 0 {
+					continue
+				}
+
+				key := deliveredKey{room: room, requested: s.requested}
+				sum := summaries[key]
+				if sum == nil {
+					sum = &deliveredSummary{time: make(map[string]time.Duration)}
+					summaries[key] = sum
+				}
+				sum.tracks++
+				if mainQuality(times) == s.requested {
+					sum.matched++
+				}
+				for q, t := range times {
+					sum.time[q] += t
+				}
+				sum.switches += switches
+				if d := s.timeToRequested.Load(); d > 0 {
+					sum.toRequested += d
+					sum.reachedTracks++
+					if d > sum.maxToRequested {
+						sum.maxToRequested = d
+					}
+				}
+			}
+		}
+	}
+	if len(summaries) == 0 {
+		return
+	}
+
+	keys := make([]deliveredKey, 0, len(summaries))
+	for key := range summaries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].room != keys[j].room {
+			return keys[i].room < keys[j].room
+		}
+		return qualityOrder[keys[i].requested] < qualityOrder[keys[j].requested]
+	})
+
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nDelivered Quality\t| Room\t| Requested\t| Tracks\t| Matched\t| Time per Quality\t| Time to Requested avg/max\t| Switches\n")
+	for _, key := range keys {
+		sum := summaries[key]
+
+		var total time.Duration
+		qualities := make([]string, 0, len(sum.time))
+		for q, t := range sum.time {
+			total += t
+			qualities = append(qualities, q)
+		}
+		sort.Slice(qualities, func(i, j int) bool {
+			return qualityOrder[qualities[i]] < qualityOrder[qualities[j]]
+		})
+		perQuality := make([]string, 0, len(qualities))
+		for _, q := range qualities {
+			perQuality = append(perQuality, fmt.Sprintf("%s %s%%", q, formatPercentage(int64(sum.time[q]), int64(total))))
+		}
+
+		// subscribers following a layout have no single requested quality
+		requested, matched, toRequested := "-", " - ", " - "
+		if key.requested != "" {
+			requested = key.requested
+			matched = fmt.Sprintf("%d/%d", sum.matched, sum.tracks)
+			if sum.reachedTracks > 0 {
+				toRequested = fmt.Sprintf("%s / %s (%d)", formatLatency(sum.toRequested/time.Duration(sum.reachedTracks)),
+					formatLatency(sum.maxToRequested), sum.reachedTracks)
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %d\n",
+			key.room, requested, sum.tracks, matched, strings.Join(perQuality, ", "), toRequested, sum.switches)
+	}
+	_ = w.Flush()
+}
+
+// unescapeRBSP removes the emulation prevention bytes of a NAL unit
+func unescapeRBSP(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros == 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,186 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// bitWriter writes the fields of an RBSP, for SPS of test streams
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) write(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte((v>>i)&0x01) << (7 - w.bits%8)
+		w.bits++
+	}
+}
+
+func (w *bitWriter) ue(v int) {
+	n := 0
+	for (v+1)>>n > 1 {
+		n++
+	}
+	w.write(0, n)
+	w.write(v+1, n+1)
+}
+
+// baselineSPS is the SPS of a baseline profile stream with the size in macroblocks, cropped at the bottom
+func baselineSPS(widthInMbs, heightInMbs, cropBottom int) []byte {
+	w := &bitWriter{}
+	w.write(66, 8) // profile_idc
+	w.write(0, 8)  // constraint flags
+	w.write(31, 8) // level_idc
+	w.ue(0)        // seq_parameter_set_id
+	w.ue(0)        // log2_max_frame_num_minus4
+	w.ue(0)        // pic_order_cnt_type
+	w.ue(0)        // log2_max_pic_order_cnt_lsb_minus4
+	w.ue(1)        // max_num_ref_frames
+	w.write(0, 1)  // gaps_in_frame_num_value_allowed_flag
+	w.ue(widthInMbs - 1)
+	w.ue(heightInMbs - 1)
+	w.write(1, 1) // frame_mbs_only_flag
+	w.write(1, 1) // direct_8x8_inference_flag
+	if cropBottom > 0 {
+		w.write(1, 1)
+		w.ue(0)
+		w.ue(0)
+		w.ue(0)
+		w.ue(cropBottom)
+	} else {
+		w.write(0, 1)
+	}
+	w.write(0, 1) // vui_parameters_present_flag
+	w.write(1, 1) // rbsp_stop_one_bit
+	return append([]byte{0x67}, w.data...)
+}
+
+func TestFrameDimensions(t *testing.T) {
+	// SPS on its own, 640x368 cropped to 640x360
+	sps := baselineSPS(40, 23, 4)
+	width, height, ok := frameDimensions(webrtc.MimeTypeH264, framePackets(sps, []byte{0x65, 0x88}))
+	require.True(t, ok)
+	require.Equal(t, 640, width)
+	require.Equal(t, 360, height)
+
+	// SPS in a STAP-A with the PPS
+	stapA := append([]byte{0x78, 0x00, byte(len(sps))}, sps...)
+	stapA = append(stapA, 0x00, 0x02, 0x68, 0xce)
+	width, height, ok = frameDimensions(webrtc.MimeTypeH264, framePackets(stapA))
+	require.True(t, ok)
+	require.Equal(t, 640, width)
+	require.Equal(t, 360, height)
+
+	// high profile SPS of x264 with emulation prevention bytes
+	sps = []byte{
+		0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78, 0x02, 0x27, 0xe5, 0xc0, 0x44, 0x00, 0x00, 0x03,
+		0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc6, 0x58,
+	}
+	width, height, ok = frameDimensions(webrtc.MimeTypeH264, framePackets(sps))
+	require.True(t, ok)
+	require.Equal(t, 1920, width)
+	require.Equal(t, 1080, height)
+
+	// frames without SPS, and a truncated one
+	_, _, ok = frameDimensions(webrtc.MimeTypeH264, framePackets([]byte{0x41, 0x9a}))
+	require.False(t, ok)
+	_, _, ok = frameDimensions(webrtc.MimeTypeH264, framePackets(sps[:6]))
+	require.False(t, ok)
+
+	// VP8 keyframe of 1280x720, and an interframe
+	vp8 := []byte{0x10, 0x50, 0x01, 0x00, 0x9d, 0x01, 0x2a, 0x00, 0x05, 0xd0, 0x02}
+	width, height, ok = frameDimensions(webrtc.MimeTypeVP8, framePackets(vp8))
+	require.True(t, ok)
+	require.Equal(t, 1280, width)
+	require.Equal(t, 720, height)
+	_, _, ok = frameDimensions(webrtc.MimeTypeVP8, framePackets([]byte{0x10, 0x51, 0x01, 0x00}))
+	require.False(t, ok)
+
+	_, _, ok = frameDimensions(webrtc.MimeTypeOpus, framePackets(vp8))
+	require.False(t, ok)
+}
+
+func TestQualityForDimensions(t *testing.T) {
+	layers := []*livekit.VideoLayer{
+		{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720},
+		{Quality: livekit.VideoQuality_MEDIUM, Width: 800, Height: 450},
+		{Quality: livekit.VideoQuality_LOW, Width: 640, Height: 360},
+	}
+
+	q, ok := qualityForDimensions(layers, 1280, 720)
+	require.True(t, ok)
+	require.Equal(t, "high", q)
+	// encoders may round to macroblocks
+	q, ok = qualityForDimensions(layers, 800, 448)
+	require.True(t, ok)
+	require.Equal(t, "medium", q)
+
+	// no simulcast
+	_, ok = qualityForDimensions(layers[:1], 1280, 720)
+	require.False(t, ok)
+
+	// every layer of the same size
+	same := []*livekit.VideoLayer{
+		{Quality: livekit.VideoQuality_HIGH, Width: 640, Height: 360},
+		{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360},
+		{Quality: livekit.VideoQuality_LOW, Width: 640, Height: 360},
+	}
+	_, ok = qualityForDimensions(same, 640, 360)
+	require.False(t, ok)
+}
+
+func TestRecordDelivered(t *testing.T) {
+	s := &trackStats{kind: TrackKindVideo, requested: "high"}
+	start := time.Now()
+
+	// the SFU starts low and moves up
+	s.recordDelivered("low", start.Add(100*time.Millisecond), start)
+	s.recordDelivered("low", start.Add(time.Second), start)
+	require.Zero(t, s.timeToRequested.Load())
+	s.recordDelivered("high", start.Add(2*time.Second), start)
+	require.Equal(t, 2*time.Second, s.timeToRequested.Load())
+	s.recordDelivered("medium", start.Add(8*time.Second), start)
+	s.recordDelivered("high", start.Add(9*time.Second), start)
+
+	times, switches := s.deliveredTimes(start.Add(12 * time.Second))
+	require.Equal(t, int64(3), switches)
+	require.Equal(t, map[string]time.Duration{
+		"low":    1900 * time.Millisecond,
+		"medium": time.Second,
+		"high":   9 * time.Second,
+	}, times)
+	require.Equal(t, "high", mainQuality(times))
+	// only the first time counts
+	require.Equal(t, 2*time.Second, s.timeToRequested.Load())
+
+	s.endedAt.Store(start.Add(12 * time.Second))
+	c := s.snapshot()
+	cTimes, cSwitches := c.deliveredTimes(time.Now())
+	require.Equal(t, times, cTimes)
+	require.Equal(t, switches, cSwitches)
+
+	// the earlier snapshot had 1.9s of low and 2s of high
+	prev := &trackStats{}
+	prev.endedAt.Store(start.Add(4 * time.Second))
+	prev.setDelivered(map[string]time.Duration{"low": 1900 * time.Millisecond, "high": 2 * time.Second}, 1)
+	dTimes, dSwitches := c.delta(prev).deliveredTimes(time.Now())
+	require.Equal(t, int64(2), dSwitches)
+	require.Equal(t, map[string]time.Duration{"medium": time.Second, "high": 7 * time.Second}, dTimes)
+
+	s.reset()
+	times, switches = s.deliveredTimes(time.Now())
+	require.Zero(t, switches)
+	// still on high since the reset
+	require.Len(t, times, 1)
+	require.Equal(t, 2*time.Second, s.timeToRequested.Load())
+}
@@ -0,0 +1,459 @@
+package loadtester
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	livekitcli "github.com/livekit/livekit-cli"
+)
+
+// OutputFormat is the format results are exported in, next to the printed tables
+type OutputFormat string
+
+const (
+	OutputNone OutputFormat = ""
+	OutputJSON OutputFormat = "json"
+	OutputCSV  OutputFormat = "csv"
+)
+
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch f := OutputFormat(s); f {
+	case OutputNone, OutputJSON, OutputCSV:
+		return f, nil
+	default:
+		return OutputNone, fmt.Errorf("unsupported output format %s, choose from json, csv", s)
+	}
+}
+
+// Export is the machine-readable result of a test
+type Export struct {
+	Metadata ExportMetadata `json:"metadata"`
+	Rooms    []*RoomExport  `json:"rooms"`
+	Phases   []*PhaseExport `json:"phases,omitempty"`
+}
+
+type ExportMetadata struct {
+	CLIVersion     string    `json:"cli_version"`
+	ServerURL      string    `json:"server_url"`
+	IdentityPrefix string    `json:"identity_prefix"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+	// test parameters, without API credentials
+	Params Params `json:"params"`
+	// offsets from Params.ClockReference latencies were measured with, per agent for coordinated tests
+	Clocks []*ClockOffset `json:"clocks,omitempty"`
+}
+
+type RoomExport struct {
+	Room    string           `json:"room"`
+	Testers []*TesterExport  `json:"testers"`
+	Totals  []*SummaryExport `json:"totals"`
+	// publishers have no tracks or summaries, their joins and what they sent are measured
+	Publishers []*TesterExport `json:"publishers,omitempty"`
+}
+
+type TesterExport struct {
+	Name         string           `json:"name"`
+	Error        string           `json:"error,omitempty"`
+	JoinDuration time.Duration    `json:"join_duration,omitempty"`
+	JoinAttempts int              `json:"join_attempts,omitempty"`
+	Tracks       []*TrackExport   `json:"tracks"`
+	Summaries    []*SummaryExport `json:"summaries"`
+	JoinMilestones
+	// tracks and simulcast layers of publishers
+	Published []*PublishedExport `json:"published,omitempty"`
+}
+
+// PublishedExport is the raw result of a published track or layer with derived figures
+type PublishedExport struct {
+	*PublishedResult
+	Bitrate float64 `json:"bitrate_bps"`
+}
+
+// TrackExport is the raw track result with derived figures
+type TrackExport struct {
+	*TrackResult
+	Bitrate    float64       `json:"bitrate_bps"`
+	LatencyAvg time.Duration `json:"latency_avg_ns"`
+	LatencyP50 time.Duration `json:"latency_p50_ns"`
+	LatencyP95 time.Duration `json:"latency_p95_ns"`
+	LatencyP99 time.Duration `json:"latency_p99_ns"`
+	RTPLost    int64         `json:"rtp_lost,omitempty"`
+	FPS        float64       `json:"fps,omitempty"`
+	// replaces the raw intervals of TrackResult
+	Intervals          []*IntervalExport `json:"intervals,omitempty"`
+	MinIntervalBitrate float64           `json:"min_interval_bitrate_bps,omitempty"`
+	MaxIntervalBitrate float64           `json:"max_interval_bitrate_bps,omitempty"`
+}
+
+// IntervalExport is the raw interval result with derived figures
+type IntervalExport struct {
+	*IntervalResult
+	Bitrate    float64       `json:"bitrate_bps"`
+	PacketRate float64       `json:"packet_rate"`
+	LatencyAvg time.Duration `json:"latency_avg_ns"`
+}
+
+// SummaryExport is a summary of one kind of tracks, for a tester or a whole room
+type SummaryExport struct {
+	Kind        TrackKind     `json:"kind"`
+	Tracks      int           `json:"tracks"`
+	Packets     int64         `json:"packets"`
+	Bytes       int64         `json:"bytes"`
+	Bitrate     float64       `json:"bitrate_bps"`
+	LatencyAvg  time.Duration `json:"latency_avg_ns"`
+	LatencyP50  time.Duration `json:"latency_p50_ns"`
+	LatencyP95  time.Duration `json:"latency_p95_ns"`
+	LatencyP99  time.Duration `json:"latency_p99_ns"`
+	LatencyMax  time.Duration `json:"latency_max_ns"`
+	Dropped     int64         `json:"dropped"`
+	DropPercent float64       `json:"drop_percent"`
+	Errors      int64         `json:"errors"`
+	// lowest and highest interval bitrate of any track, when intervals were measured
+	MinIntervalBitrate float64       `json:"min_interval_bitrate_bps,omitempty"`
+	MaxIntervalBitrate float64       `json:"max_interval_bitrate_bps,omitempty"`
+	LongestStall       time.Duration `json:"longest_stall_ns"`
+	// frame rate of an average video track, freezes per minute of video and the time to the first keyframe
+	FPS              float64       `json:"fps,omitempty"`
+	Freezes          int64         `json:"freezes,omitempty"`
+	FreezeRate       float64       `json:"freezes_per_minute,omitempty"`
+	FreezeTime       time.Duration `json:"freeze_time_ns,omitempty"`
+	FirstKeyframeAvg time.Duration `json:"first_keyframe_avg_ns,omitempty"`
+	FirstKeyframeMax time.Duration `json:"first_keyframe_max_ns,omitempty"`
+}
+
+type PhaseExport struct {
+	Name        string        `json:"name"`
+	Duration    time.Duration `json:"duration"`
+	Subscribers int           `json:"subscribers"`
+	Rooms       []*RoomExport `json:"rooms"`
+}
+
+// newExport builds the export of a finished test
+func newExport(params Params, stats map[string]map[string]*testerStats, phases []*phaseStats, startedAt, endedAt time.Time) *Export {
+	params.APIKey, params.APISecret = "", ""
+
+	e := &Export{
+		Metadata: ExportMetadata{
+			CLIVersion:     livekitcli.Version,
+			ServerURL:      params.URL,
+			IdentityPrefix: params.IdentityPrefix,
+			StartedAt:      startedAt,
+			EndedAt:        endedAt,
+			Params:         params,
+		},
+		Rooms: exportRooms(params, stats),
+	}
+
+	for _, p := range phases {
+		e.Phases = append(e.Phases, &PhaseExport{
+			Name:        p.name,
+			Duration:    p.duration,
+			Subscribers: p.subscribers,
+			Rooms:       exportRooms(params, p.stats),
+		})
+	}
+
+	return e
+}
+
+func exportRooms(params Params, stats map[string]map[string]*testerStats) []*RoomExport {
+	data, audio := params.DataPublishers > 0, params.WithAudio
+	totals := summarizeRooms(stats, data, audio)
+
+	// reuse the serializable tester results, they are sorted by room and name
+	var rooms []*RoomExport
+	for _, r := range testerResults(stats) {
+		if len(rooms) == 0 || rooms[len(rooms)-1].Room != r.Room {
+			rooms = append(rooms, &RoomExport{
+				Room:   r.Room,
+				Totals: exportSummaries(totals[r.Room]),
+			})
+		}
+		room := rooms[len(rooms)-1]
+
+		ts := stats[r.Room][r.Name]
+		tester := &TesterExport{
+			Name:           r.Name,
+			Error:          r.Error,
+			JoinDuration:   r.JoinDuration,
+			JoinAttempts:   r.JoinAttempts,
+			JoinMilestones: r.JoinMilestones,
+		}
+		if len(ts.stats) > 0 || ts.err != nil {
+			tester.Summaries = exportSummaries(getTesterSummary(ts, data, audio))
+		}
+		for _, tr := range r.Tracks {
+			tester.Tracks = append(tester.Tracks, exportTrack(tr, ts.stats[tr.TrackID]))
+		}
+
+		room.Testers = append(room.Testers, tester)
+	}
+
+	return rooms
+}
+
+// addPublishers adds the joins of publishers and what they sent to their rooms
+func (e *Export) addPublishers(publishers map[string]map[string]*testerStats) {
+	for _, r := range testerResults(publishers) {
+		var room *RoomExport
+		for _, existing := range e.Rooms {
+			if existing.Room == r.Room {
+				room = existing
+				break
+			}
+		}
+		if room == nil {
+			// a machine that only publishes
+			room = &RoomExport{Room: r.Room}
+			e.Rooms = append(e.Rooms, room)
+		}
+
+		publisher := &TesterExport{
+			Name:           r.Name,
+			Error:          r.Error,
+			JoinDuration:   r.JoinDuration,
+			JoinAttempts:   r.JoinAttempts,
+			JoinMilestones: r.JoinMilestones,
+		}
+		for _, p := range r.Published {
+			publisher.Published = append(publisher.Published, &PublishedExport{
+				PublishedResult: p,
+				Bitrate:         bitrate(p.Bytes, p.elapsed()),
+			})
+		}
+		room.Publishers = append(room.Publishers, publisher)
+	}
+}
+
+func exportTrack(tr *TrackResult, s *trackStats) *TrackExport {
+	e := &TrackExport{
+		TrackResult: tr,
+		Bitrate:     bitrate(s.bytes.Load(), s.elapsed()),
+		LatencyP50:  s.latencyHist.percentile(0.5),
+		LatencyP95:  s.latencyHist.percentile(0.95),
+		LatencyP99:  s.latencyHist.percentile(0.99),
+		RTPLost:     s.rtpLost(),
+		FPS:         s.fps(),
+	}
+	if n := s.latencyCount.Load(); n > 0 {
+		e.LatencyAvg = time.Duration(s.latency.Load() / n)
+	}
+	for _, r := range tr.Intervals {
+		e.Intervals = append(e.Intervals, &IntervalExport{
+			IntervalResult: r,
+			Bitrate:        r.bitrate(),
+			PacketRate:     r.packetRate(),
+			LatencyAvg:     r.avgLatency(),
+		})
+	}
+	e.MinIntervalBitrate, e.MaxIntervalBitrate, _ = intervalBitrates(tr.Intervals)
+
+	return e
+}
+
+func exportSummaries(summaries []*summary) []*SummaryExport {
+	var exports []*SummaryExport
+	for _, s := range summaries {
+		if s == nil {
+			continue
+		}
+
+		e := &SummaryExport{
+			Kind:             s.kind,
+			Tracks:           s.tracks,
+			Packets:          s.packets,
+			Bytes:            s.bytes,
+			Bitrate:          bitrate(s.bytes, s.elapsed),
+			LatencyAvg:       s.avgLatency(),
+			Dropped:          s.dropped,
+			DropPercent:      s.dropPercent(),
+			Errors:           s.errCount,
+			LongestStall:     s.longestStall,
+			FPS:              s.avgFPS(),
+			Freezes:          s.freezes,
+			FreezeRate:       s.freezeRate(),
+			FreezeTime:       s.freezeTime,
+			FirstKeyframeAvg: s.avgFirstKeyframe(),
+			FirstKeyframeMax: s.maxFirstKeyframe,
+		}
+		if s.intervalTracks > 0 {
+			e.MinIntervalBitrate, e.MaxIntervalBitrate = s.minIntervalBitrate, s.maxIntervalBitrate
+		}
+		if h := s.latencyHist; h != nil {
+			e.LatencyP50 = h.percentile(0.5)
+			e.LatencyP95 = h.percentile(0.95)
+			e.LatencyP99 = h.percentile(0.99)
+			e.LatencyMax = time.Duration(h.max.Load())
+		}
+		exports = append(exports, e)
+	}
+
+	return exports
+}
+
+// addRooms adds the rooms of another export, e.g. of one case of a suite, prefixing their names
+func (e *Export) addRooms(prefix string, other *Export) {
+	for _, room := range other.Rooms {
+		room.Room = prefix + room.Room
+		e.Rooms = append(e.Rooms, room)
+	}
+}
+
+// bitrate in bps
+func bitrate(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes*8) / elapsed.Seconds()
+}
+
+// exportStdout is where exports without a file are written
+var exportStdout io.Writer = os.Stdout
+
+// writeExport writes e to path in format, or to stdout when path is empty, telling out where it went
+func writeExport(out io.Writer, e *Export, format OutputFormat, path string) error {
+	if format == OutputNone {
+		return nil
+	}
+
+	w := exportStdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(e)
+	case OutputCSV:
+		err = writeCSV(w, e)
+	}
+	if err != nil {
+		return err
+	}
+
+	if path != "" {
+		fmt.Fprintf(out, "\nResults written to %s\n", path)
+	}
+
+	return nil
+}
+
+var csvHeader = []string{
+	"level", "phase", "room", "tester", "track_id", "kind", "tracks", "packets", "bytes", "bitrate_bps",
+	"latency_avg_ms", "latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "latency_max_ms",
+	"dropped", "drop_percent", "rtp_lost", "jitter_ms", "errors", "error",
+	"longest_stall_ms", "min_interval_bitrate_bps", "max_interval_bitrate_bps", "interval_start",
+	"fps", "freezes", "freeze_time_ms", "first_keyframe_ms",
+}
+
+// writeCSV writes one row per track, track interval, tester summary and room total.
+// Metadata goes into leading comment lines.
+func writeCSV(w io.Writer, e *Export) error {
+	params, err := json.Marshal(e.Metadata.Params)
+	if err != nil {
+		return err
+	}
+
+	m := e.Metadata
+	if _, err = fmt.Fprintf(w, "# cli_version: %s\n# server_url: %s\n# identity_prefix: %s\n# started_at: %s\n# ended_at: %s\n# params: %s\n",
+		m.CLIVersion, m.ServerURL, m.IdentityPrefix,
+		m.StartedAt.Format(time.RFC3339), m.EndedAt.Format(time.RFC3339), params); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err = cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	rows := csvRooms("", e.Rooms)
+	for _, p := range e.Phases {
+		rows = append(rows, csvRooms(p.Name, p.Rooms)...)
+	}
+	for _, row := range rows {
+		if err = cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRooms(phase string, rooms []*RoomExport) [][]string {
+	var rows [][]string
+	for _, room := range rooms {
+		for _, tester := range room.Testers {
+			for _, t := range tester.Tracks {
+				rows = append(rows, []string{
+					"track", phase, room.Room, tester.Name, t.TrackID, string(t.Kind), "1",
+					strconv.FormatInt(t.Packets, 10), strconv.FormatInt(t.Bytes, 10), formatFloat(t.Bitrate),
+					formatMs(t.LatencyAvg), formatMs(t.LatencyP50), formatMs(t.LatencyP95), formatMs(t.LatencyP99),
+					formatMs(time.Duration(t.LatencyMax)),
+					strconv.FormatInt(t.Dropped, 10), formatFloat(percent(t.Dropped, t.Packets+t.Dropped)),
+					strconv.FormatInt(t.RTPLost, 10), formatMs(time.Duration(t.Jitter)), "0", "",
+					formatMs(t.LongestStall), formatFloat(t.MinIntervalBitrate), formatFloat(t.MaxIntervalBitrate), "",
+					formatFloat(t.FPS), strconv.FormatInt(t.Freezes, 10), formatMs(t.FreezeTime), formatMs(t.FirstKeyframe),
+				})
+				for _, r := range t.Intervals {
+					rows = append(rows, []string{
+						"interval", phase, room.Room, tester.Name, t.TrackID, string(t.Kind), "1",
+						strconv.FormatInt(r.Packets, 10), strconv.FormatInt(r.Bytes, 10), formatFloat(r.Bitrate),
+						formatMs(r.LatencyAvg), "", "", "", "",
+						strconv.FormatInt(r.Dropped, 10), formatFloat(percent(r.Dropped, r.Packets+r.Dropped)),
+						"", "", "", "", "", "", "", r.Start.Format(time.RFC3339Nano),
+						"", "", "", "",
+					})
+				}
+			}
+			for _, s := range tester.Summaries {
+				rows = append(rows, csvSummary("tester", phase, room.Room, tester.Name, tester.Error, s))
+			}
+		}
+		for _, s := range room.Totals {
+			rows = append(rows, csvSummary("room", phase, room.Room, "", "", s))
+		}
+	}
+
+	return rows
+}
+
+func csvSummary(level, phase, room, tester, errString string, s *SummaryExport) []string {
+	return []string{
+		level, phase, room, tester, "", string(s.Kind), strconv.Itoa(s.Tracks),
+		strconv.FormatInt(s.Packets, 10), strconv.FormatInt(s.Bytes, 10), formatFloat(s.Bitrate),
+		formatMs(s.LatencyAvg), formatMs(s.LatencyP50), formatMs(s.LatencyP95), formatMs(s.LatencyP99), formatMs(s.LatencyMax),
+		strconv.FormatInt(s.Dropped, 10), formatFloat(s.DropPercent), "", "", strconv.FormatInt(s.Errors, 10), errString,
+		formatMs(s.LongestStall), formatFloat(s.MinIntervalBitrate), formatFloat(s.MaxIntervalBitrate), "",
+		formatFloat(s.FPS), strconv.FormatInt(s.Freezes, 10), formatMs(s.FreezeTime), formatMs(s.FirstKeyframeAvg),
+	}
+}
+
+func percent(num, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(num) / float64(total) * 100
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
+
+func formatMs(d time.Duration) string {
+	return formatFloat(float64(d) / float64(time.Millisecond))
+}
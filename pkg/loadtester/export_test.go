@@ -0,0 +1,102 @@
+package loadtester
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testStats() map[string]map[string]*testerStats {
+	now := time.Now()
+	track := &trackStats{trackID: "TR_1", kind: TrackKindVideo}
+	track.startedAt.Store(now.Add(-10 * time.Second))
+	track.endedAt.Store(now)
+	track.packets.Store(1000)
+	track.bytes.Store(1250000)
+	track.recordLatency(int64(20 * time.Millisecond))
+	track.recordLatency(int64(40 * time.Millisecond))
+
+	return map[string]map[string]*testerStats{
+		"room": {
+			"Sub 0 in room": {stats: map[string]*trackStats{"TR_1": track}},
+			"Sub 1 in room": {stats: map[string]*trackStats{}, err: errTest},
+		},
+	}
+}
+
+var errTest = errors.New("could not connect")
+
+func TestExport(t *testing.T) {
+	params := Params{TesterParams: TesterParams{
+		URL:            "wss://example.livekit.cloud",
+		APIKey:         "key",
+		APISecret:      "secret",
+		IdentityPrefix: "abc",
+	}}
+	e := newExport(params, testStats(), nil, time.Now().Add(-time.Minute), time.Now())
+
+	require.Equal(t, "wss://example.livekit.cloud", e.Metadata.ServerURL)
+	require.Equal(t, "abc", e.Metadata.IdentityPrefix)
+	require.Empty(t, e.Metadata.Params.APISecret)
+	require.Len(t, e.Rooms, 1)
+	require.Len(t, e.Rooms[0].Testers, 2)
+
+	total := e.Rooms[0].Totals[0]
+	require.Equal(t, TrackKindVideo, total.Kind)
+	require.Equal(t, 1, total.Tracks)
+	require.Equal(t, int64(1), total.Errors)
+	require.InDelta(t, 1000000, total.Bitrate, 1)
+	require.Equal(t, 30*time.Millisecond, total.LatencyAvg)
+	require.Equal(t, 40*time.Millisecond, total.LatencyMax)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, json.NewEncoder(buf).Encode(e))
+	decoded := &Export{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	require.Equal(t, e.Rooms[0].Totals, decoded.Rooms[0].Totals)
+	require.NotContains(t, buf.String(), "secret")
+
+	buf.Reset()
+	require.NoError(t, writeCSV(buf, e))
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	rows, err := csv.NewReader(strings.NewReader(strings.Join(lines, "\n"))).ReadAll()
+	require.NoError(t, err)
+	// header, one track, two tester summaries and the room total
+	require.Len(t, rows, 5)
+	require.Equal(t, csvHeader, rows[0])
+	require.Equal(t, "track", rows[1][0])
+	require.Equal(t, "room", rows[4][0])
+}
+
+func TestWriteExportStdout(t *testing.T) {
+	var buf bytes.Buffer
+	stdout := exportStdout
+	exportStdout = &buf
+	defer func() { exportStdout = stdout }()
+
+	// a publisher-only run has no subscriber stats
+	e := newExport(Params{}, nil, nil, time.Now(), time.Now())
+	e.addPublishers(map[string]map[string]*testerStats{
+		"room": {"Pub 0 in room": {published: []*PublishedResult{{TrackID: "TR_1", Kind: TrackKindVideo, Packets: 100}}}},
+	})
+	require.NoError(t, writeExport(io.Discard, e, OutputJSON, ""))
+
+	// nothing but the export is written, so that it can be piped
+	decoded := &Export{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	require.Len(t, decoded.Rooms, 1)
+	require.Len(t, decoded.Rooms[0].Publishers, 1)
+	require.Equal(t, int64(100), decoded.Rooms[0].Publishers[0].Published[0].Packets)
+}
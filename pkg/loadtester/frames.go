@@ -0,0 +1,233 @@
+package loadtester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// a gap between frames of more than freezeFactor times the average frame interval is a freeze
+	freezeFactor = 3
+	// frame intervals averaged before freezes are detected
+	minFreezeIntervals = 5
+)
+
+// frameTracker rebuilds what a viewer would see from the frames of the sample builder: frames count
+// once a keyframe made the track decodable, and stop counting after packet loss until the next keyframe.
+// It is only used from the goroutine reading the track and reports into trackStats.
+type frameTracker struct {
+	mimeType string
+	// frames of a LoadTestProvider do not depend on each other, every one is as good as a keyframe
+	synthetic    bool
+	subscribedAt time.Time
+
+	decodable   bool
+	lastFrameAt time.Time
+	// frame intervals that were not freezes, for the average frame interval
+	intervals   int64
+	intervalSum time.Duration
+}
+
+func newFrameTracker(mimeType string, synthetic bool, subscribedAt time.Time) *frameTracker {
+	return &frameTracker{
+		mimeType:     mimeType,
+		synthetic:    synthetic,
+		subscribedAt: subscribedAt,
+	}
+}
+
+// push records a frame completed at now
+func (f *frameTracker) push(packets []*rtp.Packet, now time.Time, stats *trackStats) {
+	if !f.decodable {
+		if !f.synthetic && !isKeyframe(f.mimeType, packets) {
+			return
+		}
+		f.decodable = true
+		if stats.firstKeyframe.Load() == 0 {
+			stats.firstKeyframe.Store(now.Sub(f.subscribedAt))
+		}
+	}
+
+	stats.frames.Inc()
+	if !f.lastFrameAt.IsZero() {
+		gap := now.Sub(f.lastFrameAt)
+		if f.intervals >= minFreezeIntervals && gap > freezeFactor*f.intervalSum/time.Duration(f.intervals) {
+			stats.freezes.Inc()
+			stats.freezeTime.Add(gap)
+		} else {
+			f.intervals++
+			f.intervalSum += gap
+		}
+	}
+	f.lastFrameAt = now
+}
+
+// lost is called when the sample builder dropped packets, the picture freezes until the next keyframe
+func (f *frameTracker) lost() {
+	if !f.synthetic {
+		f.decodable = false
+	}
+}
+
+// isKeyframe tells whether the packets of a frame start a keyframe, frames of unknown codecs never do
+func isKeyframe(mimeType string, packets []*rtp.Packet) bool {
+	for _, pkt := range packets {
+		payload := pkt.Payload
+		if len(payload) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(mimeType) {
+		case strings.ToLower(webrtc.MimeTypeH264):
+			if h264IsKeyframe(payload) {
+				return true
+			}
+
+		case strings.ToLower(webrtc.MimeTypeVP8):
+			p := &codecs.VP8Packet{}
+			frame, err := p.Unmarshal(payload)
+			// P bit of the frame tag in the first partition
+			if err == nil && p.S == 1 && p.PID == 0 && len(frame) > 0 {
+				return frame[0]&0x01 == 0
+			}
+
+		case strings.ToLower(webrtc.MimeTypeVP9):
+			p := &codecs.VP9Packet{}
+			if _, err := p.Unmarshal(payload); err == nil && p.B {
+				return !p.P && p.SID == 0
+			}
+
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+const (
+	h264NALTypeIDR   = 5
+	h264NALTypeSTAPA = 24
+	h264NALTypeFUA   = 28
+)
+
+func h264IsKeyframe(payload []byte) bool {
+	switch payload[0] & 0x1f {
+	case h264NALTypeIDR:
+		return true
+
+	case h264NALTypeSTAPA:
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			if payload[i+2]&0x1f == h264NALTypeIDR {
+				return true
+			}
+			i += 2 + size
+		}
+
+	case h264NALTypeFUA:
+		// start bit and type of the fragmented NAL unit
+		return len(payload) > 1 && payload[1]&0x80 != 0 && payload[1]&0x1f == h264NALTypeIDR
+	}
+
+	return false
+}
+
+// fps is the received frame rate over the time the track was measured
+func (s *trackStats) fps() float64 {
+	elapsed := s.elapsed()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.frames.Load()) / elapsed.Seconds()
+}
+
+// formatFrames shows frame rate and freezes of video tracks, other tracks show dashes
+func formatFrames(s *trackStats) (fps, freezes string) {
+	if s.kind != TrackKindVideo || s.frames.Load() == 0 {
+		return " - ", " - "
+	}
+
+	return fmt.Sprintf("%.1f", s.fps()),
+		fmt.Sprintf("%d (%s)", s.freezes.Load(), s.freezeTime.Load().Round(time.Millisecond))
+}
+
+func (s *summary) addFrames(other *summary) {
+	s.frames += other.frames
+	s.freezes += other.freezes
+	s.freezeTime += other.freezeTime
+	s.trackTime += other.trackTime
+	s.firstKeyframe += other.firstKeyframe
+	s.keyframeTracks += other.keyframeTracks
+	if other.maxFirstKeyframe > s.maxFirstKeyframe {
+		s.maxFirstKeyframe = other.maxFirstKeyframe
+	}
+}
+
+// freezeRate is freezes per minute of video, the KPI real clients report
+func (s *summary) freezeRate() float64 {
+	if s.trackTime <= 0 {
+		return 0
+	}
+	return float64(s.freezes) / s.trackTime.Minutes()
+}
+
+// avgFPS is the frame rate of an average track
+func (s *summary) avgFPS() float64 {
+	if s.trackTime <= 0 {
+		return 0
+	}
+	return float64(s.frames) / s.trackTime.Seconds()
+}
+
+func (s *summary) avgFirstKeyframe() time.Duration {
+	if s.keyframeTracks == 0 {
+		return 0
+	}
+	return s.firstKeyframe / time.Duration(s.keyframeTracks)
+}
+
+// printFrames shows what viewers saw per room: frame rate, freezes and how long the first keyframe took.
+// Nothing is printed unless some video track received frames.
+func (t *LoadTest) printFrames(rooms map[string][]*summary) {
+	names := make([]string, 0, len(rooms))
+	for room, summaries := range rooms {
+		for _, s := range summaries {
+			if s.kind == TrackKindVideo && s.frames > 0 {
+				names = append(names, room)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nFrames\t| Room\t| Tracks\t| Avg FPS\t| Freezes\t| Freezes/min\t| Freeze Time\t| First Keyframe avg/max\n")
+	for _, room := range names {
+		for _, s := range rooms[room] {
+			if s.kind != TrackKindVideo || s.frames == 0 {
+				continue
+			}
+
+			frozen := " - "
+			if s.trackTime > 0 {
+				frozen = fmt.Sprintf("%s (%.2f%%)", s.freezeTime.Round(time.Millisecond),
+					float64(s.freezeTime)/float64(s.trackTime)*100)
+			}
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %d\t| %.1f\t| %d\t| %.2f\t| %s\t| %s / %s\n",
+				room, s.tracks, s.avgFPS(), s.freezes, s.freezeRate(), frozen,
+				formatLatency(s.avgFirstKeyframe()), formatLatency(s.maxFirstKeyframe))
+		}
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,107 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func framePackets(payloads ...[]byte) []*rtp.Packet {
+	packets := make([]*rtp.Packet, 0, len(payloads))
+	for _, p := range payloads {
+		packets = append(packets, &rtp.Packet{Payload: p})
+	}
+	return packets
+}
+
+func TestIsKeyframe(t *testing.T) {
+	// IDR slice
+	require.True(t, isKeyframe(webrtc.MimeTypeH264, framePackets([]byte{0x65, 0x88})))
+	// non-IDR slice
+	require.False(t, isKeyframe(webrtc.MimeTypeH264, framePackets([]byte{0x41, 0x9a})))
+	// SEI on its own, then an FU-A start of an IDR slice
+	require.True(t, isKeyframe(webrtc.MimeTypeH264, framePackets([]byte{0x06, 0x05}, []byte{0x7c, 0x85, 0x88})))
+	// STAP-A with SPS and IDR
+	require.True(t, isKeyframe(webrtc.MimeTypeH264, framePackets([]byte{0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x65, 0x88})))
+	// STAP-A with SPS and PPS only
+	require.False(t, isKeyframe(webrtc.MimeTypeH264, framePackets([]byte{0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xce})))
+
+	// VP8 start of partition 0, frame tag with P=0 and P=1
+	require.True(t, isKeyframe(webrtc.MimeTypeVP8, framePackets([]byte{0x10, 0x50, 0x01, 0x00})))
+	require.False(t, isKeyframe(webrtc.MimeTypeVP8, framePackets([]byte{0x10, 0x51, 0x01, 0x00})))
+
+	// VP9 start of frame (B=1), not inter-picture predicted (P=0) and predicted (P=1)
+	require.True(t, isKeyframe(webrtc.MimeTypeVP9, framePackets([]byte{0x08, 0xaa})))
+	require.False(t, isKeyframe(webrtc.MimeTypeVP9, framePackets([]byte{0x48, 0xaa})))
+
+	require.False(t, isKeyframe(webrtc.MimeTypeOpus, framePackets([]byte{0x78})))
+}
+
+func TestFrameTracker(t *testing.T) {
+	s := &trackStats{kind: TrackKindVideo}
+	start := time.Now()
+	f := newFrameTracker(webrtc.MimeTypeH264, false, start)
+	key := framePackets([]byte{0x65})
+	delta := framePackets([]byte{0x41})
+
+	// frames before the first keyframe can not be decoded
+	f.push(delta, start.Add(100*time.Millisecond), s)
+	require.Zero(t, s.frames.Load())
+
+	at := start.Add(200 * time.Millisecond)
+	f.push(key, at, s)
+	require.Equal(t, 200*time.Millisecond, s.firstKeyframe.Load())
+	for i := 0; i < 10; i++ {
+		at = at.Add(40 * time.Millisecond)
+		f.push(delta, at, s)
+	}
+	require.Equal(t, int64(11), s.frames.Load())
+	require.Zero(t, s.freezes.Load())
+
+	// a gap of more than three frame intervals
+	at = at.Add(500 * time.Millisecond)
+	f.push(delta, at, s)
+	require.Equal(t, int64(1), s.freezes.Load())
+	require.Equal(t, 500*time.Millisecond, s.freezeTime.Load())
+
+	// after loss, the picture stays frozen until the next keyframe
+	f.lost()
+	f.push(delta, at.Add(40*time.Millisecond), s)
+	require.Equal(t, int64(12), s.frames.Load())
+	at = at.Add(time.Second)
+	f.push(key, at, s)
+	require.Equal(t, int64(13), s.frames.Load())
+	require.Equal(t, int64(2), s.freezes.Load())
+	require.Equal(t, 1500*time.Millisecond, s.freezeTime.Load())
+	// only the first keyframe counts
+	require.Equal(t, 200*time.Millisecond, s.firstKeyframe.Load())
+
+	s.reset()
+	require.Zero(t, s.frames.Load())
+	require.Equal(t, 200*time.Millisecond, s.firstKeyframe.Load())
+}
+
+func TestFrameSummary(t *testing.T) {
+	ts := &testerStats{stats: make(map[string]*trackStats)}
+	for i, id := range []string{"a", "b"} {
+		s := &trackStats{trackID: id, kind: TrackKindVideo}
+		s.startedAt.Store(time.Unix(0, 0))
+		s.endedAt.Store(time.Unix(60, 0))
+		s.frames.Store(1800)
+		s.freezes.Store(int64(i * 3))
+		s.freezeTime.Store(time.Duration(i) * time.Second)
+		s.firstKeyframe.Store(time.Duration(i+1) * 100 * time.Millisecond)
+		ts.stats[id] = s
+	}
+
+	s := getTesterTracksSummary(ts, TrackKindVideo)
+	require.Equal(t, 30.0, s.avgFPS())
+	require.Equal(t, int64(3), s.freezes)
+	require.Equal(t, 1.5, s.freezeRate())
+	require.Equal(t, time.Second, s.freezeTime)
+	require.Equal(t, 150*time.Millisecond, s.avgFirstKeyframe())
+	require.Equal(t, 200*time.Millisecond, s.maxFirstKeyframe)
+}
@@ -0,0 +1,158 @@
+package loadtester
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// Latencies are kept in logarithmic buckets, bucketsPerOctave per doubling of latency starting at 1µs.
+// A bucket's upper bound is within 5% of any latency counted in it, up to ~70 minutes.
+const (
+	bucketsPerOctave = 16
+	histogramBuckets = 32 * bucketsPerOctave
+)
+
+// latencyHistogram can be recorded into and read from concurrently
+type latencyHistogram struct {
+	buckets [histogramBuckets]atomic.Int64
+	count   atomic.Int64
+	max     atomic.Int64
+}
+
+func histogramBucket(latency int64) int {
+	us := float64(latency) / float64(time.Microsecond)
+	if us < 1 {
+		return 0
+	}
+
+	b := int(math.Log2(us) * bucketsPerOctave)
+	if b >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return b
+}
+
+// bucketUpperBound is the highest latency counted in bucket b
+func bucketUpperBound(b int) time.Duration {
+	return time.Duration(math.Exp2(float64(b+1)/bucketsPerOctave) * float64(time.Microsecond))
+}
+
+func (h *latencyHistogram) record(latency int64) {
+	h.buckets[histogramBucket(latency)].Inc()
+	h.count.Inc()
+	for {
+		m := h.max.Load()
+		if latency <= m || h.max.CompareAndSwap(m, latency) {
+			return
+		}
+	}
+}
+
+// merge adds the counts of other into h
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	for i := range other.buckets {
+		if n := other.buckets[i].Load(); n != 0 {
+			h.buckets[i].Add(n)
+		}
+	}
+	h.count.Add(other.count.Load())
+	if m := other.max.Load(); m > h.max.Load() {
+		h.max.Store(m)
+	}
+}
+
+// subtract removes the counts of an earlier copy of h. The max can not be undone and is kept.
+func (h *latencyHistogram) subtract(prev *latencyHistogram) {
+	for i := range prev.buckets {
+		if n := prev.buckets[i].Load(); n != 0 {
+			h.buckets[i].Sub(n)
+		}
+	}
+	h.count.Sub(prev.count.Load())
+}
+
+func (h *latencyHistogram) reset() {
+	for i := range h.buckets {
+		h.buckets[i].Store(0)
+	}
+	h.count.Store(0)
+	h.max.Store(0)
+}
+
+// percentile returns the latency below which q (0-1) of the recorded latencies are
+func (h *latencyHistogram) percentile(q float64) time.Duration {
+	count := h.count.Load()
+	if count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	maxLatency := time.Duration(h.max.Load())
+	var seen int64
+	for i := range h.buckets {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			if upper := bucketUpperBound(i); upper < maxLatency {
+				return upper
+			}
+			return maxLatency
+		}
+	}
+
+	return maxLatency
+}
+
+// nonEmpty returns the counts of non-empty buckets by index, for serialization
+func (h *latencyHistogram) nonEmpty() map[string]int64 {
+	buckets := make(map[string]int64)
+	for i := range h.buckets {
+		if n := h.buckets[i].Load(); n != 0 {
+			buckets[strconv.Itoa(i)] = n
+		}
+	}
+	return buckets
+}
+
+func (h *latencyHistogram) load(buckets map[string]int64, maxLatency int64) {
+	for k, n := range buckets {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= histogramBuckets {
+			continue
+		}
+		h.buckets[i].Add(n)
+		h.count.Add(n)
+	}
+	h.max.Store(maxLatency)
+}
+
+// formatPercentiles shows p50/p95/p99/max
+func formatPercentiles(h *latencyHistogram) string {
+	if h == nil || h.count.Load() == 0 {
+		return " - "
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s",
+		formatLatency(h.percentile(0.5)),
+		formatLatency(h.percentile(0.95)),
+		formatLatency(h.percentile(0.99)),
+		formatLatency(time.Duration(h.max.Load())))
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
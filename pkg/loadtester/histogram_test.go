@@ -0,0 +1,39 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := &latencyHistogram{}
+	for i := 1; i <= 100; i++ {
+		h.record(int64(time.Duration(i) * time.Millisecond))
+	}
+
+	within := func(expected, actual time.Duration) {
+		require.InEpsilon(t, float64(expected), float64(actual), 0.05, "expected %s, got %s", expected, actual)
+	}
+	within(50*time.Millisecond, h.percentile(0.5))
+	within(95*time.Millisecond, h.percentile(0.95))
+	within(99*time.Millisecond, h.percentile(0.99))
+	require.Equal(t, 100*time.Millisecond, h.percentile(1))
+
+	// a single stall shows up in the tail, not hidden in an average
+	other := &latencyHistogram{}
+	other.record(int64(5 * time.Second))
+	h.merge(other)
+	require.Equal(t, int64(101), h.count.Load())
+	require.Equal(t, 5*time.Second, time.Duration(h.max.Load()))
+	within(51*time.Millisecond, h.percentile(0.5))
+
+	loaded := &latencyHistogram{}
+	loaded.load(h.nonEmpty(), h.max.Load())
+	require.Equal(t, h.percentile(0.99), loaded.percentile(0.99))
+
+	h.subtract(other)
+	require.Equal(t, int64(100), h.count.Load())
+	within(99*time.Millisecond, h.percentile(0.99))
+}
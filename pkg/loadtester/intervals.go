@@ -0,0 +1,224 @@
+package loadtester
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// IntervalResult holds what a track received during one interval of Params.Interval
+type IntervalResult struct {
+	Start        time.Time     `json:"start"`
+	Duration     time.Duration `json:"duration"`
+	Packets      int64         `json:"packets"`
+	Bytes        int64         `json:"bytes"`
+	Dropped      int64         `json:"dropped"`
+	LatencyTotal int64         `json:"latency_total_ns"`
+	LatencyCount int64         `json:"latency_count"`
+}
+
+// bitrate in bps
+func (r *IntervalResult) bitrate() float64 {
+	return bitrate(r.Bytes, r.Duration)
+}
+
+// packetRate in packets per second
+func (r *IntervalResult) packetRate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Packets) / r.Duration.Seconds()
+}
+
+func (r *IntervalResult) avgLatency() time.Duration {
+	if r.LatencyCount == 0 {
+		return 0
+	}
+	return time.Duration(r.LatencyTotal / r.LatencyCount)
+}
+
+// intervalRecorder keeps the closed intervals of a track and the counters at the start of the open one
+type intervalRecorder struct {
+	lock   sync.Mutex
+	closed []*IntervalResult
+	// counters when the open interval started, zero time before the first one was closed
+	at           time.Time
+	packets      int64
+	bytes        int64
+	dropped      int64
+	latency      int64
+	latencyCount int64
+}
+
+// closeInterval ends the open interval at now and starts the next one
+func (s *trackStats) closeInterval(now time.Time) {
+	r := &s.intervals
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	start := r.at
+	if start.IsZero() {
+		start = s.startedAt.Load()
+	}
+	if start.IsZero() || !now.After(start) {
+		return
+	}
+
+	packets, bytes, dropped := s.packets.Load(), s.bytes.Load(), s.dropped.Load()
+	latency, latencyCount := s.latency.Load(), s.latencyCount.Load()
+	r.closed = append(r.closed, &IntervalResult{
+		Start:        start,
+		Duration:     now.Sub(start),
+		Packets:      packets - r.packets,
+		Bytes:        bytes - r.bytes,
+		Dropped:      dropped - r.dropped,
+		LatencyTotal: latency - r.latency,
+		LatencyCount: latencyCount - r.latencyCount,
+	})
+	r.at, r.packets, r.bytes, r.dropped, r.latency, r.latencyCount = now, packets, bytes, dropped, latency, latencyCount
+}
+
+// intervalResults returns the closed intervals, oldest first
+func (s *trackStats) intervalResults() []*IntervalResult {
+	s.intervals.lock.Lock()
+	defer s.intervals.lock.Unlock()
+
+	return append([]*IntervalResult{}, s.intervals.closed...)
+}
+
+func (s *trackStats) setIntervals(intervals []*IntervalResult) {
+	s.intervals.lock.Lock()
+	defer s.intervals.lock.Unlock()
+
+	s.intervals.closed = intervals
+}
+
+func (s *trackStats) resetIntervals() {
+	s.intervals.lock.Lock()
+	defer s.intervals.lock.Unlock()
+
+	r := &s.intervals
+	r.closed = nil
+	r.at = time.Time{}
+	r.packets, r.bytes, r.dropped, r.latency, r.latencyCount = 0, 0, 0, 0, 0
+}
+
+// recordArrival keeps track of the longest time without packets, counting from the start of the track
+func (s *trackStats) recordArrival(now time.Time) {
+	last := s.lastPacketAt.Load()
+	if last.IsZero() {
+		last = s.startedAt.Load()
+	}
+	if gap := now.Sub(last); gap > s.longestStall.Load() {
+		s.longestStall.Store(gap)
+	}
+	s.lastPacketAt.Store(now)
+}
+
+// stall is the longest time without packets up to end, including the time since the last packet
+func (s *trackStats) stall(end time.Time) time.Duration {
+	last := s.lastPacketAt.Load()
+	if last.IsZero() {
+		last = s.startedAt.Load()
+	}
+	longest := s.longestStall.Load()
+	if !last.IsZero() && end.Sub(last) > longest {
+		longest = end.Sub(last)
+	}
+
+	return longest
+}
+
+// intervalBitrates returns the lowest and highest bitrate of full intervals. Intervals under half
+// the length of the longest one, i.e. the partial first and last ones, are left out.
+func intervalBitrates(intervals []*IntervalResult) (float64, float64, bool) {
+	var longest time.Duration
+	for _, r := range intervals {
+		if r.Duration > longest {
+			longest = r.Duration
+		}
+	}
+
+	var lowest, highest float64
+	found := false
+	for _, r := range intervals {
+		if r.Duration < longest/2 {
+			continue
+		}
+		b := r.bitrate()
+		if !found || b < lowest {
+			lowest = b
+		}
+		if !found || b > highest {
+			highest = b
+		}
+		found = true
+	}
+
+	return lowest, highest, found
+}
+
+// addIntervalBitrates widens the summary's interval bitrate range by that of tracks tracks
+func (s *summary) addIntervalBitrates(lowest, highest float64, tracks int) {
+	if tracks == 0 {
+		return
+	}
+	if s.intervalTracks == 0 || lowest < s.minIntervalBitrate {
+		s.minIntervalBitrate = lowest
+	}
+	if s.intervalTracks == 0 || highest > s.maxIntervalBitrate {
+		s.maxIntervalBitrate = highest
+	}
+	s.intervalTracks += tracks
+}
+
+// recordIntervals closes intervals of all tracks every params.interval until done is closed
+func (t *LoadTester) recordIntervals(done chan struct{}) {
+	ticker := time.NewTicker(t.params.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			t.stats.Range(func(_, value interface{}) bool {
+				value.(*trackStats).closeInterval(now)
+				return true
+			})
+		}
+	}
+}
+
+// printIntervals shows the range of interval bitrates and the longest stall per room and kind
+func (t *LoadTest) printIntervals(rooms map[string][]*summary) {
+	if t.Params.Interval == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(rooms))
+	for room := range rooms {
+		names = append(names, room)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprintf(w, "\nIntervals (%s)\t| Room\t| Kind\t| Tracks\t| Lowest Bitrate\t| Highest Bitrate\t| Longest Stall\n", t.Params.Interval)
+	for _, room := range names {
+		for _, s := range rooms[room] {
+			if s.tracks == 0 {
+				continue
+			}
+
+			lowest, highest := " - ", " - "
+			if s.intervalTracks > 0 {
+				lowest, highest = formatBps(s.minIntervalBitrate), formatBps(s.maxIntervalBitrate)
+			}
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\n",
+				room, s.kind, s.tracks, lowest, highest, s.longestStall.Round(time.Millisecond))
+		}
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,76 @@
+package loadtester
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervals(t *testing.T) {
+	start := time.Now()
+	s := &trackStats{trackID: "TR_1", kind: TrackKindVideo}
+	s.startedAt.Store(start)
+
+	// 1mbps, then a 5s stall without packets, then 1mbps again
+	receive := func(from, to time.Duration) {
+		for at := from; at < to; at += 10 * time.Millisecond {
+			s.recordArrival(start.Add(at))
+			s.bytes.Add(1250)
+			s.packets.Inc()
+		}
+	}
+	receive(0, 10*time.Second)
+	s.closeInterval(start.Add(10 * time.Second))
+	receive(15*time.Second, 20*time.Second)
+	s.closeInterval(start.Add(20 * time.Second))
+	receive(20*time.Second, 22*time.Second)
+	s.closeInterval(start.Add(22 * time.Second))
+
+	intervals := s.intervalResults()
+	require.Len(t, intervals, 3)
+	require.Equal(t, start.Add(10*time.Second), intervals[1].Start)
+	require.Equal(t, int64(500), intervals[1].Packets)
+	require.InDelta(t, 100, intervals[0].packetRate(), 0.01)
+
+	// the partial last interval is left out
+	lowest, highest, ok := intervalBitrates(intervals)
+	require.True(t, ok)
+	require.InDelta(t, 500000, lowest, 1)
+	require.InDelta(t, 1000000, highest, 1)
+
+	require.Equal(t, 5*time.Second+10*time.Millisecond, s.longestStall.Load())
+	require.Equal(t, 8*time.Second, s.stall(start.Add(30*time.Second).Add(-10*time.Millisecond)))
+
+	// delta keeps the intervals that started after the earlier snapshot
+	prev := &trackStats{}
+	prev.endedAt.Store(start.Add(10 * time.Second))
+	require.Len(t, s.delta(prev).intervalResults(), 2)
+
+	s.reset()
+	require.Empty(t, s.intervalResults())
+	require.Zero(t, s.longestStall.Load())
+}
+
+func TestStopConcurrent(t *testing.T) {
+	tester := NewLoadTester(TesterParams{Room: "room", name: "Sub 0 in room", interval: time.Second}, livekit.VideoQuality_HIGH)
+	tester.room = lksdk.CreateRoom(&lksdk.RoomCallback{})
+	tester.intervalsDone = make(chan struct{})
+	tester.running.Store(true)
+
+	// closing the intervals twice would panic
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tester.Stop()
+		}()
+	}
+	wg.Wait()
+	require.False(t, tester.IsRunning())
+	require.Nil(t, tester.intervalsDone)
+}
@@ -0,0 +1,111 @@
+package loadtester
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// JoinMilestones are the times from the start of joining until a tester reached each step,
+// zero for steps it did not reach. Joining itself is TesterResult.JoinDuration, which includes
+// the signal connection as the server SDK does not tell when that is up.
+type JoinMilestones struct {
+	FirstPublished  time.Duration `json:"first_published_ns,omitempty"`
+	FirstSubscribed time.Duration `json:"first_subscribed_ns,omitempty"`
+	FirstRTP        time.Duration `json:"first_rtp_ns,omitempty"`
+	FirstKeyframe   time.Duration `json:"first_keyframe_ns,omitempty"`
+}
+
+// joinProgress records JoinMilestones while the tester runs
+type joinProgress struct {
+	start           atomic.Time
+	firstPublished  atomic.Duration
+	firstSubscribed atomic.Duration
+	firstRTP        atomic.Duration
+	firstKeyframe   atomic.Duration
+}
+
+// reached records the time to a milestone the first time it is reached
+func (t *LoadTester) reached(milestone *atomic.Duration) {
+	if milestone.Load() != 0 {
+		return
+	}
+	start := t.join.start.Load()
+	if start.IsZero() {
+		return
+	}
+	milestone.CompareAndSwap(0, time.Since(start))
+}
+
+func (t *LoadTester) joinMilestones() JoinMilestones {
+	return JoinMilestones{
+		FirstPublished:  t.join.firstPublished.Load(),
+		FirstSubscribed: t.join.firstSubscribed.Load(),
+		FirstRTP:        t.join.firstRTP.Load(),
+		FirstKeyframe:   t.join.firstKeyframe.Load(),
+	}
+}
+
+// joinSteps names the milestones of a tester in the order they happen
+var joinSteps = []string{"joined", "first published", "first subscribed", "first RTP", "first keyframe"}
+
+func (ts *testerStats) joinSteps() []time.Duration {
+	m := ts.milestones
+	return []time.Duration{ts.joinDuration, m.FirstPublished, m.FirstSubscribed, m.FirstRTP, m.FirstKeyframe}
+}
+
+// printJoins shows per room how publishers and subscribers got in, and how long each step took from the start of joining
+func (t *LoadTest) printJoins(subscribers, publishers map[string]map[string]*testerStats) {
+	type roleStats struct {
+		room, role string
+		stats      map[string]*testerStats
+	}
+	var roles []roleStats
+	for room, stats := range publishers {
+		roles = append(roles, roleStats{room: room, role: "publishers", stats: stats})
+	}
+	for room, stats := range subscribers {
+		roles = append(roles, roleStats{room: room, role: "subscribers", stats: stats})
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].room != roles[j].room {
+			return roles[i].room < roles[j].room
+		}
+		return roles[i].role < roles[j].role
+	})
+
+	summaries := make([]*joinSummary, 0, len(roles))
+	for _, r := range roles {
+		summaries = append(summaries, getJoinSummary(r.stats))
+	}
+
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nJoins\t| Room\t| Role\t| Testers\t| Joined\t| Failed\t| Retries\n")
+	for i, r := range roles {
+		s := summaries[i]
+		if s.testers == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %d\t| %d\t| %d\n",
+			r.room, r.role, s.testers, s.joined, s.failed, s.retries)
+	}
+	_ = w.Flush()
+
+	w = tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nJoin Timings\t| Room\t| Role\t| Step\t| Testers\t| Avg\t| p50/p95/p99/max\n")
+	for i, r := range roles {
+		s := summaries[i]
+		for step, name := range joinSteps {
+			if s.reached[step] == 0 {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %s\t| %d\t| %s\t| %s\n",
+				r.room, r.role, name, s.reached[step],
+				formatLatency(s.stepTotal[step]/time.Duration(s.reached[step])), formatPercentiles(&s.steps[step]))
+		}
+	}
+	_ = w.Flush()
+}
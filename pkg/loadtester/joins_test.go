@@ -0,0 +1,62 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReached(t *testing.T) {
+	tester := &LoadTester{}
+
+	// nothing is recorded before joining started
+	tester.reached(&tester.join.firstRTP)
+	require.Zero(t, tester.join.firstRTP.Load())
+
+	tester.join.start.Store(time.Now().Add(-time.Second))
+	tester.reached(&tester.join.firstRTP)
+	first := tester.join.firstRTP.Load()
+	require.GreaterOrEqual(t, first, time.Second)
+
+	// only the first time counts
+	time.Sleep(time.Millisecond)
+	tester.reached(&tester.join.firstRTP)
+	require.Equal(t, first, tester.join.firstRTP.Load())
+}
+
+func TestJoinSummary(t *testing.T) {
+	stats := map[string]*testerStats{
+		"Sub 0": {
+			joinAttempts: 1,
+			joinDuration: 200 * time.Millisecond,
+			milestones: JoinMilestones{
+				FirstSubscribed: 300 * time.Millisecond,
+				FirstRTP:        400 * time.Millisecond,
+				FirstKeyframe:   500 * time.Millisecond,
+			},
+		},
+		"Sub 1": {
+			joinAttempts: 3,
+			joinDuration: 2200 * time.Millisecond,
+			milestones: JoinMilestones{
+				FirstSubscribed: 2300 * time.Millisecond,
+			},
+		},
+		// never joined
+		"Sub 2": {joinAttempts: 10},
+		// not started
+		"Sub 3": {},
+	}
+
+	s := getJoinSummary(stats)
+	require.Equal(t, 3, s.testers)
+	require.Equal(t, 2, s.joined)
+	require.Equal(t, 1, s.failed)
+	require.Equal(t, 11, s.retries)
+
+	// joined, first published, first subscribed, first RTP, first keyframe
+	require.Equal(t, []int{2, 0, 2, 1, 1}, s.reached)
+	require.Equal(t, 2400*time.Millisecond, s.stepTotal[0])
+	require.Equal(t, int64(2200*time.Millisecond), s.steps[0].max.Load())
+}
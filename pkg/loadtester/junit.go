@@ -0,0 +1,103 @@
+package loadtester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes one test suite per room, with a test case per threshold.
+// Rooms without thresholds get a single case holding their summary.
+func writeJUnit(path, name string, rooms map[string][]*summary, checks []*thresholdCheck) error {
+	names := make([]string, 0, len(rooms))
+	for room := range rooms {
+		names = append(names, room)
+	}
+	sort.Strings(names)
+
+	report := &junitTestSuites{Name: name}
+	for _, room := range names {
+		suite := &junitTestSuite{Name: room}
+		for _, c := range checks {
+			if c.room != room {
+				continue
+			}
+
+			tc := &junitTestCase{
+				Name:      c.name,
+				ClassName: room,
+				SystemOut: fmt.Sprintf("%s (limit %s)", c.value, c.limit),
+			}
+			if c.failed {
+				tc.Failure = &junitFailure{Message: c.String(), Text: c.String()}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, &junitTestCase{
+				Name:      "summary",
+				ClassName: room,
+				SystemOut: junitSummary(rooms[room]),
+			})
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
+
+func junitSummary(summaries []*summary) string {
+	var lines []string
+	for _, s := range summaries {
+		if s.tracks == 0 {
+			lines = append(lines, fmt.Sprintf("%s: no tracks, %d errors", s.kind, s.errCount))
+			continue
+		}
+
+		latency, dropped := formatStrings(s.packets, s.latency, s.latencyCount, s.dropped)
+		lines = append(lines, fmt.Sprintf("%s: %d tracks, %s, latency %s, dropped %s, %d errors",
+			s.kind, s.tracks, formatBitrate(s.bytes, s.elapsed), latency, dropped, s.errCount))
+	}
+
+	return strings.Join(lines, "\n")
+}
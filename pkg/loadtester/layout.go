@@ -0,0 +1,217 @@
+package loadtester
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+// Layout simulates how a meeting client renders a room, which decides what it subscribes to and at what size
+type Layout string
+
+const (
+	// LayoutNone receives every track at full quality
+	LayoutNone Layout = ""
+	// LayoutSpeaker shows the active speaker large and everyone else as thumbnails
+	LayoutSpeaker Layout = "speaker"
+	Layout3x3     Layout = "3x3"
+	Layout4x4     Layout = "4x4"
+	Layout5x5     Layout = "5x5"
+)
+
+// size of the simulated client window that grid tiles share
+const (
+	layoutViewportWidth  = 1280
+	layoutViewportHeight = 720
+)
+
+func ParseLayout(s string) (Layout, error) {
+	switch l := Layout(strings.ToLower(strings.TrimSpace(s))); l {
+	case LayoutNone, LayoutSpeaker, Layout3x3, Layout4x4, Layout5x5:
+		return l, nil
+	default:
+		return LayoutNone, fmt.Errorf("unsupported layout %s, choose from speaker, 3x3, 4x4, 5x5", s)
+	}
+}
+
+// gridSize is the number of tiles per row and column, zero when the layout is not a grid
+func (l Layout) gridSize() int {
+	switch l {
+	case Layout3x3:
+		return 3
+	case Layout4x4:
+		return 4
+	case Layout5x5:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func (l Layout) tileDimensions() (uint32, uint32) {
+	n := uint32(l.gridSize())
+	if n == 0 {
+		return layoutViewportWidth, layoutViewportHeight
+	}
+
+	return layoutViewportWidth / n, layoutViewportHeight / n
+}
+
+// layoutState is what a tester needs to remember to follow its layout
+type layoutState struct {
+	// participant SID => video publication
+	videoPubs map[string]*lksdk.RemoteTrackPublication
+	speaker   string
+	// grid: track SIDs shown in a tile, and video tracks waiting for a free tile
+	tiles   map[string]struct{}
+	waiting []*lksdk.RemoteTrackPublication
+}
+
+func (t *LoadTester) usesLayout() bool {
+	return t.params.SameRoom && t.params.Layout != LayoutNone
+}
+
+// subscribeWithLayout subscribes to a newly published track, for grids only while there is a free tile
+func (t *LoadTester) subscribeWithLayout(publication *lksdk.RemoteTrackPublication) {
+	n := t.params.Layout.gridSize()
+	if n == 0 || publication.Kind() != lksdk.TrackKindVideo {
+		publication.SetSubscribed(true)
+		return
+	}
+
+	t.lock.Lock()
+	if len(t.layout.tiles) >= n*n {
+		t.layout.waiting = append(t.layout.waiting, publication)
+		t.lock.Unlock()
+		return
+	}
+	t.layout.tiles[publication.SID()] = struct{}{}
+	t.lock.Unlock()
+
+	publication.SetSubscribed(true)
+}
+
+// onLayoutTrackUnpublished frees the tile of a track that went away and hands it to a waiting track
+func (t *LoadTester) onLayoutTrackUnpublished(publication *lksdk.RemoteTrackPublication, rp *lksdk.RemoteParticipant) {
+	if !t.usesLayout() || publication.Kind() != lksdk.TrackKindVideo {
+		return
+	}
+
+	t.lock.Lock()
+	if pub, ok := t.layout.videoPubs[rp.SID()]; ok && pub.SID() == publication.SID() {
+		delete(t.layout.videoPubs, rp.SID())
+	}
+
+	var next *lksdk.RemoteTrackPublication
+	if _, ok := t.layout.tiles[publication.SID()]; ok {
+		delete(t.layout.tiles, publication.SID())
+		if len(t.layout.waiting) > 0 {
+			next = t.layout.waiting[0]
+			t.layout.waiting = t.layout.waiting[1:]
+			t.layout.tiles[next.SID()] = struct{}{}
+		}
+	} else {
+		for i, pub := range t.layout.waiting {
+			if pub.SID() == publication.SID() {
+				t.layout.waiting = append(t.layout.waiting[:i], t.layout.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	t.lock.Unlock()
+
+	if next != nil {
+		next.SetSubscribed(true)
+	}
+}
+
+// applyLayout requests the dimensions a subscribed video track is rendered at
+func (t *LoadTester) applyLayout(pub *lksdk.RemoteTrackPublication, rp *lksdk.RemoteParticipant) {
+	if t.params.Layout.gridSize() > 0 {
+		pub.SetVideoDimensions(t.params.Layout.tileDimensions())
+		return
+	}
+
+	t.lock.Lock()
+	t.layout.videoPubs[rp.SID()] = pub
+	if t.layout.speaker == "" {
+		// until someone speaks, the first participant is on the main stage
+		t.layout.speaker = rp.SID()
+	}
+	isSpeaker := t.layout.speaker == rp.SID()
+	t.lock.Unlock()
+
+	t.setSpeakerDimensions(pub, isSpeaker)
+}
+
+func (t *LoadTester) onActiveSpeakersChanged(speakers []lksdk.Participant) {
+	if !t.usesLayout() || t.params.Layout != LayoutSpeaker {
+		return
+	}
+
+	var speaker string
+	for _, p := range speakers {
+		if _, ok := p.(*lksdk.RemoteParticipant); ok {
+			speaker = p.SID()
+			break
+		}
+	}
+
+	t.lock.Lock()
+	previous, current, changed := t.layout.switchSpeaker(speaker)
+	t.lock.Unlock()
+	if !changed {
+		return
+	}
+
+	if previous != nil {
+		t.setSpeakerDimensions(previous, false)
+	}
+	if current != nil {
+		t.setSpeakerDimensions(current, true)
+	}
+}
+
+// switchSpeaker puts speaker on the main stage, returning the videos of the previous and the new speaker.
+// changed is false when nobody remote is speaking or the speaker is already on the main stage.
+func (s *layoutState) switchSpeaker(speaker string) (previous, current *lksdk.RemoteTrackPublication, changed bool) {
+	if speaker == "" || speaker == s.speaker {
+		return nil, nil, false
+	}
+
+	previous = s.videoPubs[s.speaker]
+	current = s.videoPubs[speaker]
+	s.speaker = speaker
+	return previous, current, true
+}
+
+func (t *LoadTester) setSpeakerDimensions(pub *lksdk.RemoteTrackPublication, isSpeaker bool) {
+	if width, height, ok := speakerDimensions(pub.TrackInfo(), isSpeaker); ok {
+		pub.SetVideoDimensions(width, height)
+	}
+}
+
+// speakerDimensions picks the largest layer the track is published with for the speaker,
+// and the smallest one for thumbnails. Tracks without layers are rendered at their own size.
+func speakerDimensions(info *livekit.TrackInfo, isSpeaker bool) (uint32, uint32, bool) {
+	layers := info.GetLayers()
+	if len(layers) == 0 {
+		if info.GetWidth() == 0 || info.GetHeight() == 0 {
+			return 0, 0, false
+		}
+		return info.GetWidth(), info.GetHeight(), true
+	}
+
+	picked := layers[0]
+	for _, l := range layers[1:] {
+		larger := l.Width*l.Height > picked.Width*picked.Height
+		smaller := l.Width*l.Height < picked.Width*picked.Height
+		if (isSpeaker && larger) || (!isSpeaker && smaller) {
+			picked = l
+		}
+	}
+
+	return picked.Width, picked.Height, true
+}
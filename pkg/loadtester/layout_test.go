@@ -0,0 +1,111 @@
+package loadtester
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+func TestParseLayout(t *testing.T) {
+	for _, s := range []string{"", "speaker", "3x3", "4x4", "5x5"} {
+		l, err := ParseLayout(s)
+		require.NoError(t, err)
+		require.Equal(t, Layout(s), l)
+	}
+
+	l, err := ParseLayout(" Speaker ")
+	require.NoError(t, err)
+	require.Equal(t, LayoutSpeaker, l)
+
+	_, err = ParseLayout("6x6")
+	require.Error(t, err)
+}
+
+func TestTileDimensions(t *testing.T) {
+	cases := map[Layout]struct {
+		tiles         int
+		width, height uint32
+	}{
+		Layout3x3:     {tiles: 9, width: 426, height: 240},
+		Layout4x4:     {tiles: 16, width: 320, height: 180},
+		Layout5x5:     {tiles: 25, width: 256, height: 144},
+		LayoutSpeaker: {tiles: 0, width: layoutViewportWidth, height: layoutViewportHeight},
+		LayoutNone:    {tiles: 0, width: layoutViewportWidth, height: layoutViewportHeight},
+	}
+
+	for l, c := range cases {
+		n := l.gridSize()
+		require.Equal(t, c.tiles, n*n, l)
+		width, height := l.tileDimensions()
+		require.Equal(t, c.width, width, l)
+		require.Equal(t, c.height, height, l)
+	}
+}
+
+func TestSpeakerDimensions(t *testing.T) {
+	simulcast := &livekit.TrackInfo{
+		Width:  1280,
+		Height: 720,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180},
+			{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720},
+			{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360},
+		},
+	}
+	portrait := &livekit.TrackInfo{Width: 540, Height: 960}
+
+	cases := map[string]struct {
+		info          *livekit.TrackInfo
+		isSpeaker     bool
+		width, height uint32
+		ok            bool
+	}{
+		"speaker":             {info: simulcast, isSpeaker: true, width: 1280, height: 720, ok: true},
+		"thumbnail":           {info: simulcast, isSpeaker: false, width: 320, height: 180, ok: true},
+		"no layers speaker":   {info: portrait, isSpeaker: true, width: 540, height: 960, ok: true},
+		"no layers thumbnail": {info: portrait, isSpeaker: false, width: 540, height: 960, ok: true},
+		"unknown size":        {info: &livekit.TrackInfo{}, isSpeaker: true},
+		"no track info":       {isSpeaker: true},
+	}
+
+	for name, c := range cases {
+		width, height, ok := speakerDimensions(c.info, c.isSpeaker)
+		require.Equal(t, c.ok, ok, name)
+		require.Equal(t, c.width, width, name)
+		require.Equal(t, c.height, height, name)
+	}
+}
+
+func TestSwitchSpeaker(t *testing.T) {
+	alice, bob := &lksdk.RemoteTrackPublication{}, &lksdk.RemoteTrackPublication{}
+	s := &layoutState{
+		videoPubs: map[string]*lksdk.RemoteTrackPublication{"PA_alice": alice, "PA_bob": bob},
+		speaker:   "PA_alice",
+	}
+
+	// nobody remote is speaking, or the speaker keeps speaking
+	_, _, changed := s.switchSpeaker("")
+	require.False(t, changed)
+	_, _, changed = s.switchSpeaker("PA_alice")
+	require.False(t, changed)
+
+	previous, current, changed := s.switchSpeaker("PA_bob")
+	require.True(t, changed)
+	require.Same(t, alice, previous)
+	require.Same(t, bob, current)
+	require.Equal(t, "PA_bob", s.speaker)
+
+	// a speaker without video takes the main stage, bob's video becomes a thumbnail
+	previous, current, changed = s.switchSpeaker("PA_carol")
+	require.True(t, changed)
+	require.Same(t, bob, previous)
+	require.Nil(t, current)
+
+	previous, current, changed = s.switchSpeaker("PA_alice")
+	require.True(t, changed)
+	require.Nil(t, previous)
+	require.Same(t, alice, current)
+}
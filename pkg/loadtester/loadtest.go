@@ -3,6 +3,7 @@ package loadtester
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/livekit/livekit-cli/pkg/provider"
 	"github.com/livekit/protocol/livekit"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
@@ -18,7 +20,13 @@ import (
 
 type LoadTest struct {
 	Params Params
-	lock   sync.Mutex
+	// tables and progress are printed here, stdout unless changed
+	Out  io.Writer
+	lock sync.Mutex
+	// nil unless metrics are served
+	metrics *metricsCollector
+	// data publishers as requested, Params has them limited to the subscribers
+	dataPublishers int
 }
 
 type Params struct {
@@ -44,10 +52,56 @@ type Params struct {
 	LowQualityViewer   int
 	DataPacketByteSize int
 	DataBitrate        int
+	// staged subscriber load, replaces Duration when set
+	Phases []Phase
+	// replace testers while the test is running
+	Churn ChurnParams
+	// checked once the test is done, failing thresholds make Run return a ThresholdError
+	Thresholds Thresholds
+	// JUnit XML report of rooms and thresholds is written here when set
+	JUnitFile string
+	// results are exported in this format to OutputFile, or stdout when no file is set
+	Output     OutputFormat
+	OutputFile string
+	// show live per room stats while the test is running, instead of a spinner
+	Dashboard bool
+	// address to serve Prometheus metrics on while the test is running, e.g. :9090
+	MetricsAddr string
+	// length of the intervals received tracks are also measured in, zero for totals only
+	Interval time.Duration
+	// stats are reset once this has passed after all testers joined, Duration starts after it
+	WarmUp time.Duration
+	// report the warm-up like a phase, rather than discarding it
+	ReportWarmUp bool
+	// videos are read from the manifest in this directory instead of the built-in ones, see provider.Manifest
+	MediaDir string
+	// publishers send frames of a LoadTestProvider at SyntheticBitrate (bps) and SyntheticFPS instead of video,
+	// for bandwidth-only tests at any bitrate
+	Synthetic        bool
+	SyntheticBitrate int
+	SyntheticFPS     int
+	// publishers also subscribe to the other tracks of their room, like the participants of a call
+	PublishersSubscribe bool
+	// send times and latencies use the clock of this reference, either an NTP server as host[:port]
+	// or the URL of a coordinator, so that publishers and subscribers on different machines agree
+	ClockReference string
 
 	TesterParams
 }
 
+type testResult struct {
+	// room => tester name => stats, over the whole test
+	stats map[string]map[string]*testerStats
+	// per phase stats when the test was run with phases
+	phases []*phaseStats
+	// rooms the test created
+	rooms []string
+	// publishers by room and name, with their join milestones and what they sent
+	publishers map[string]map[string]*testerStats
+	// offset from the reference clock, nil without Params.ClockReference
+	clock *ClockOffset
+}
+
 type trackParams struct {
 	roomName   string
 	resolution string
@@ -56,7 +110,9 @@ type trackParams struct {
 
 func NewLoadTest(params Params) *LoadTest {
 	l := &LoadTest{
-		Params: params,
+		Params:         params,
+		Out:            os.Stdout,
+		dataPublishers: params.DataPublishers,
 	}
 
 	if l.Params.NumPerSecond == 0 {
@@ -68,6 +124,19 @@ func NewLoadTest(params Params) *LoadTest {
 		l.Params.NumPerSecond = 10
 	}
 
+	if l.Params.Synthetic && l.Params.SyntheticBitrate == 0 {
+		l.Params.SyntheticBitrate = defaultSyntheticBitrate
+	}
+
+	if l.Params.Synthetic && l.Params.SyntheticFPS == 0 {
+		l.Params.SyntheticFPS = defaultSyntheticFPS
+	}
+
+	if len(l.Params.Phases) > 0 {
+		// rooms are sized for the largest phase
+		l.Params.Subscribers = maxPhaseSubscribers(l.Params.Phases)
+	}
+
 	if l.Params.DataPublishers > l.Params.Subscribers {
 		l.Params.DataPublishers = l.Params.Subscribers
 	}
@@ -108,17 +177,51 @@ func NewLoadTest(params Params) *LoadTest {
 }
 
 func (t *LoadTest) Run(ctx context.Context) error {
-	stats, err := t.run(ctx, t.Params)
+	if t.Params.IdentityPrefix == "" {
+		// fixed here rather than in run, so that it can be exported
+		t.Params.IdentityPrefix = randStringRunes(5)
+	}
+
+	startedAt := time.Now()
+	result, err := t.run(ctx, t.Params)
 	if err != nil {
 		return err
 	}
+	endedAt := time.Now()
 
 	if t.Params.Subscribers == 0 {
-		fmt.Printf("No subscribers, skipping stats\n")
+		fmt.Fprintf(t.Out, "No subscribers, skipping stats\n")
+		// publishers still report how they got in and what they sent
+		t.printJoins(nil, result.publishers)
+		t.printPublishers(result.publishers)
+	} else {
+		t.printStats(result.stats)
+		t.printJoins(result.stats, result.publishers)
+		t.printPublishers(result.publishers)
+
+		if len(result.phases) > 0 {
+			t.printPhases(result.phases)
+		}
 
-		return nil
+		printClocks(t.Out, result.clocks(), result.stats)
 	}
 
+	e := newExport(t.Params, result.stats, result.phases, startedAt, endedAt)
+	e.addPublishers(result.publishers)
+	e.Metadata.Clocks = result.clocks()
+	if err := writeExport(t.Out, e, t.Params.Output, t.Params.OutputFile); err != nil {
+		return err
+	}
+
+	if t.Params.Subscribers == 0 {
+		// nothing is received, thresholds are checked against the publishers' errors
+		return t.checkResults(result.publishers)
+	}
+
+	return t.checkResults(result.stats)
+}
+
+func (t *LoadTest) printStats(stats map[string]map[string]*testerStats) {
 	summaries := make(map[string]map[string][]*summary)
 	statsKeys := make([]string, 0, len(stats))
 	for k := range stats {
@@ -127,7 +230,7 @@ func (t *LoadTest) Run(ctx context.Context) error {
 
 	sort.Strings(statsKeys)
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
 	for _, roomStats := range statsKeys {
 		fmt.Fprintf(w, "\nStatistics for room %s\n", roomStats)
 
@@ -144,21 +247,29 @@ func (t *LoadTest) Run(ctx context.Context) error {
 
 		for _, subName := range subRoomStatsKeys {
 			if len(subRoomStats[subName].stats) == 0 {
+				// testers that failed to connect still count as errors
+				if subRoomStats[subName].err != nil {
+					summaries[roomStats][subName] = getTesterSummary(subRoomStats[subName], t.Params.DataPublishers > 0, t.Params.WithAudio)
+				}
 				continue
 			}
 
 			summaries[roomStats][subName] = getTesterSummary(subRoomStats[subName], t.Params.DataPublishers > 0, t.Params.WithAudio)
 
-			_, _ = fmt.Fprintf(w, "\n%s\t| Track\t| Kind\t| Pkts\t| Bitrate\t| Latency\t| Dropped\n", subName)
+			_, _ = fmt.Fprintf(w, "\n%s\t| Track\t| Kind\t| Pkts\t| Bitrate\t| Latency\t| p50/p95/p99/max\t| Dropped\t| Lost\t| Dups\t| Out of order\t| Jitter\t| FPS\t| Freezes\n", subName)
 			for _, stat := range subRoomStats[subName].stats {
 
 				latency, dropped := formatStrings(
 					stat.packets.Load(), stat.latency.Load(),
 					stat.latencyCount.Load(), stat.dropped.Load())
 
-				_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\n",
+				lost, dups, outOfOrder, jitter := formatRTPStats(stat)
+				fps, freezes := formatFrames(stat)
+
+				_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\t| %s\n",
 					stat.trackID, stat.kind, stat.packets.Load(),
-					formatBitrate(stat.bytes.Load(), time.Since(stat.startedAt.Load())), latency, dropped)
+					formatBitrate(stat.bytes.Load(), stat.elapsed()), latency, formatPercentiles(&stat.latencyHist), dropped,
+					lost, dups, outOfOrder, jitter, fps, freezes)
 
 			}
 			_ = w.Flush()
@@ -166,7 +277,7 @@ func (t *LoadTest) Run(ctx context.Context) error {
 	}
 
 	if len(summaries) == 0 {
-		return nil
+		return
 	}
 
 	sumKeys := make([]string, 0, len(summaries))
@@ -178,9 +289,9 @@ func (t *LoadTest) Run(ctx context.Context) error {
 
 	// summary
 	for _, name := range sumKeys {
-		w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
 		fmt.Fprintf(w, "\nSummary for room %s\n", name)
-		_, _ = fmt.Fprint(w, "\nSummary\t| Tester\t| Kind\t| Tracks\t| Bitrate\t| Latency\t| Total Dropped\t| Error\n")
+		_, _ = fmt.Fprint(w, "\nSummary\t| Tester\t| Kind\t| Tracks\t| Bitrate\t| Latency\t| p50/p95/p99/max\t| Total Dropped\t| Error\n")
 
 		subSummariesKeys := make([]string, 0, len(summaries[name]))
 		for k := range summaries[name] {
@@ -202,8 +313,8 @@ func (t *LoadTest) Run(ctx context.Context) error {
 
 				sBitrate := formatBitrate(s.bytes, s.elapsed)
 
-				_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %s\n",
-					subName, s.kind, s.tracks, sBitrate, sLatency, sDropped, s.errString)
+				_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %s\t| %s\n",
+					subName, s.kind, s.tracks, sBitrate, sLatency, formatPercentiles(s.latencyHist), sDropped, s.errString)
 			}
 		}
 
@@ -212,13 +323,16 @@ func (t *LoadTest) Run(ctx context.Context) error {
 			sLatency, sDropped := formatStrings(
 				stat.packets, stat.latency, stat.latencyCount, stat.dropped)
 			// avg bitrate per sub
-			sBitrate := fmt.Sprintf("%s (%s avg)",
-				formatBitrate(stat.bytes, stat.elapsed),
-				formatBitrate(stat.bytes/int64(stat.tracks), stat.elapsed),
-			)
+			sBitrate := " - "
+			if stat.tracks > 0 {
+				sBitrate = fmt.Sprintf("%s (%s avg)",
+					formatBitrate(stat.bytes, stat.elapsed),
+					formatBitrate(stat.bytes/int64(stat.tracks), stat.elapsed),
+				)
+			}
 
-			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %d\n",
-				"Total", stat.kind, stat.tracks, sBitrate, sLatency, sDropped, stat.errCount)
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %s\t| %d\n",
+				"Total", stat.kind, stat.tracks, sBitrate, sLatency, formatPercentiles(stat.latencyHist), sDropped, stat.errCount)
 		}
 
 		_ = w.Flush()
@@ -226,11 +340,15 @@ func (t *LoadTest) Run(ctx context.Context) error {
 
 	_ = w.Flush()
 
-	return nil
+	t.printLayers(stats)
+	t.printDelivered(stats)
+	rooms := summarizeRooms(stats, t.Params.DataPublishers > 0, t.Params.WithAudio)
+	t.printFrames(rooms)
+	t.printIntervals(rooms)
 }
 
 func (t *LoadTest) GetResolutions(isRemote bool) []string {
-	resolutions := strings.Split(t.Params.VideoResolution, " ")
+	resolutions := strings.Fields(t.Params.VideoResolution)
 
 	countPublishers := t.Params.VideoPublishers
 	if isRemote {
@@ -239,19 +357,38 @@ func (t *LoadTest) GetResolutions(isRemote bool) []string {
 
 	if len(resolutions) < countPublishers {
 		for i := len(resolutions); i < countPublishers; i++ {
-			resolutions = append(resolutions, "1080p")
+			resolutions = append(resolutions, provider.DefaultResolution())
 		}
 	}
 
 	return resolutions
 }
 
-func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[string]*testerStats, error) {
+// GetCodecs returns the video codec of every publisher, publishers take turns using the codecs given
+func (t *LoadTest) GetCodecs() []string {
+	codecs := strings.Fields(t.Params.VideoCodec)
+	if len(codecs) == 0 {
+		codecs = []string{""}
+	}
+
+	perPublisher := make([]string, t.Params.VideoPublishers)
+	for i := range perPublisher {
+		perPublisher[i] = codecs[i%len(codecs)]
+	}
+
+	return perPublisher
+}
+
+func (t *LoadTest) run(ctx context.Context, params Params) (*testResult, error) {
+	params.out = t.Out
+
 	if params.Room == "" {
 		params.Room = "load-test"
 	}
 
-	params.IdentityPrefix = randStringRunes(5)
+	if params.IdentityPrefix == "" {
+		params.IdentityPrefix = randStringRunes(5)
+	}
 
 	if params.RemotePublishers == 0 && params.VideoPublishers == 0 {
 		return nil, fmt.Errorf("cannot have zero publishers")
@@ -265,6 +402,49 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		return nil, fmt.Errorf("cannot have remote publishers and video publishers")
 	}
 
+	if params.Interval < 0 {
+		return nil, fmt.Errorf("interval cannot be negative")
+	}
+
+	if params.Layout != LayoutNone && !params.SameRoom {
+		return nil, fmt.Errorf("layout can only be used together with same-room")
+	}
+
+	if params.MediaDir != "" {
+		if err := provider.UseMediaDir(params.MediaDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if params.Synthetic {
+		if params.SyntheticBitrate <= 0 || params.SyntheticFPS <= 0 {
+			return nil, fmt.Errorf("synthetic bitrate and frame rate must be positive")
+		}
+		if _, err := NewLoadTestProvider(uint32(params.SyntheticBitrate), uint32(params.SyntheticFPS)); err != nil {
+			return nil, fmt.Errorf("invalid synthetic publishers: %w", err)
+		}
+	}
+
+	var clock *ClockOffset
+	if params.ClockReference != "" {
+		var err error
+		if clock, err = estimateClockOffset(ctx, params.ClockReference); err != nil {
+			return nil, err
+		}
+		provider.SetClockOffset(clock.Offset)
+		defer provider.SetClockOffset(0)
+
+		fmt.Fprintf(t.Out, "Clock is %s off %s (±%s)\n", clock.Offset.Round(time.Microsecond), clock.Reference, clock.Uncertainty.Round(time.Microsecond))
+	}
+
+	if params.WarmUp < 0 {
+		return nil, fmt.Errorf("warm-up cannot be negative")
+	}
+
+	if params.WarmUp > 0 && len(params.Phases) > 0 {
+		return nil, fmt.Errorf("warm-up cannot be combined with phases, which report every stage separately")
+	}
+
 	isRemote := params.RemotePublishers > 0
 
 	expectedTracks := params.VideoPublishers
@@ -289,7 +469,16 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		participantStrings = append(participantStrings, fmt.Sprintf("%d remote publishers", params.RemotePublishers))
 	}
 
-	fmt.Printf("Starting load test with %s\n", strings.Join(participantStrings, ", "))
+	fmt.Fprintf(t.Out, "Starting load test with %s\n", strings.Join(participantStrings, ", "))
+
+	if params.MetricsAddr != "" {
+		t.metrics = newMetricsCollector()
+		stop, err := serveMetrics(params.MetricsAddr, t.metrics, t.Out)
+		if err != nil {
+			return nil, err
+		}
+		defer stop()
+	}
 
 	var publishers, testers []*LoadTester
 	group, _ := errgroup.WithContext(ctx)
@@ -297,6 +486,29 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 	numStarted := float64(0)
 	errs := syncmap.Map{}
 	resolutions := t.GetResolutions(isRemote)
+	codecs := t.GetCodecs()
+	for _, codec := range codecs {
+		if err := provider.CheckCodec(codec); err != nil {
+			return nil, err
+		}
+	}
+	for _, resolution := range resolutions {
+		if provider.GetVideoResolution(resolution) == nil {
+			return nil, fmt.Errorf("unsupported video resolution %s", resolution)
+		}
+	}
+	if !isRemote && !params.Synthetic {
+		// media files are checked before anyone joins rather than by every publisher
+		checked := make(map[string]bool)
+		for i := 0; i < params.VideoPublishers; i++ {
+			if key := resolutions[i] + "/" + codecs[i]; !checked[key] {
+				if err := provider.CheckVideo(resolutions[i], codecs[i], params.Simulcast); err != nil {
+					return nil, err
+				}
+				checked[key] = true
+			}
+		}
+	}
 
 	maxPublishers := params.VideoPublishers
 	if isRemote {
@@ -314,6 +526,14 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 
 	subParams := []*trackParams{}
 
+	var churn *churner
+	if params.Churn.enabled() {
+		if len(params.Phases) > 0 {
+			return nil, fmt.Errorf("churn cannot be combined with phases")
+		}
+		churn = newChurner(params, &errs, t.metrics)
+	}
+
 	for i := 0; i < maxPublishers; i++ {
 		var room string
 
@@ -345,25 +565,11 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		}
 
 		if !isRemote {
-			testerPubParams := prepareTesterPubParams(params, i, room, roomID)
-			testerVideo := NewLoadTester(testerPubParams, livekit.VideoQuality_HIGH)
-
+			testerVideo, err := startPublisher(params, prepareTesterPubParams(params, i, room, roomID), resolution, codecs[i])
 			publishers = append(publishers, testerVideo)
-
-			if err := testerVideo.Start(); err != nil {
-				fmt.Println(errors.Wrapf(err, "could not connect %s", testerPubParams.name))
-				if trackParam != nil {
-					trackParam.err = err
-				}
-
-				continue
-			}
-
-			var err error
-			if params.Simulcast {
-				_, err = testerVideo.PublishSimulcastTrack("video-simulcast", resolution, params.VideoCodec)
-			} else {
-				_, err = testerVideo.PublishVideoTrack("video", resolution, params.VideoCodec)
+			t.metrics.add(&errs, testerVideo)
+			if churn != nil {
+				churn.addPublisher(testerVideo, room, roomID, resolution, codecs[i])
 			}
 			if err != nil {
 				if trackParam != nil {
@@ -373,80 +579,36 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 				continue
 			}
 
-			if params.WithAudio {
-				if _, err = testerVideo.PublishAudioTrack("audio"); err != nil {
-					if trackParam != nil {
-						trackParam.err = err
-					}
-
-					continue
-				}
-			}
-
 			numStarted++
 		}
 	}
 
+	if len(params.Phases) > 0 {
+		result, err := t.runPhases(ctx, params, subParams, publishers)
+		if result != nil {
+			result.publishers = collectStats(publishers, &errs)
+			result.clock = clock
+		}
+		return result, err
+	}
+
 	ready := make(chan struct{})
 
 	for _, subParam := range subParams {
-		high := params.HighQualityViewer
-		medium := params.MediumQualityView
-		low := params.LowQualityViewer
-
-		var dataPublisher int = 0
-
 		for j := 0; j < params.Subscribers; j++ {
-			testerSubParams := params.TesterParams
-			testerSubParams.Sequence = j
-			testerSubParams.Subscribe = true
-			testerSubParams.Resolution = subParam.resolution
-			testerSubParams.SameRoom = params.SameRoom
-			testerSubParams.IdentityPrefix += fmt.Sprintf("_sub%s", subParam.roomName)
-			testerSubParams.Room = subParam.roomName
-			testerSubParams.name = fmt.Sprintf("Sub %d in %s", j, subParam.roomName)
-			if subParam.err != nil {
-				errs.Store(testerSubParams.name, subParam.err)
-				if !params.SameRoom {
-					continue
-				}
-			}
-
-			quality := livekit.VideoQuality_HIGH
-
-			if high > 0 {
-				quality = livekit.VideoQuality_HIGH
-				high--
-			} else if medium > 0 {
-				quality = livekit.VideoQuality_MEDIUM
-				medium--
-			} else if low > 0 {
-				quality = livekit.VideoQuality_LOW
-				low--
+			tester := newSubscriber(params, subParam, j, viewerQuality(params, j), &errs)
+			if tester == nil {
+				continue
 			}
-
-			tester := NewLoadTester(testerSubParams, quality)
 			testers = append(testers, tester)
+			t.metrics.add(&errs, tester)
 
+			publishData := j < params.DataPublishers
+			if churn != nil {
+				churn.addSubscriber(tester, subParam, viewerQuality(params, j), publishData)
+			}
 			group.Go(func() error {
-				if err := tester.Start(); err != nil {
-					fmt.Println(errors.Wrapf(err, "could not connect %s", testerSubParams.name))
-					errs.Store(testerSubParams.name, err)
-					return nil
-				}
-
-				if dataPublisher >= params.DataPublishers {
-					return nil
-				}
-
-				dataPublisher++
-
-				if err := tester.PublishData(
-					params.DataPacketByteSize, params.DataBitrate, livekit.DataPacket_RELIABLE, ready); err != nil {
-					errs.Store(testerSubParams.name, err)
-					return nil
-				}
-
+				startSubscriber(params, tester, publishData, ready, &errs)
 				return nil
 			})
 			numStarted++
@@ -455,7 +617,7 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 
 	done := make(chan struct{})
 
-	runWaiting(done, "Waiting all subscribers will be connect")
+	runWaiting(t.Out, done, "Waiting all subscribers will be connect")
 
 	// throttle pace of join events
 	for {
@@ -485,7 +647,7 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		close(ready)
 		done <- struct{}{}
 
-		fmt.Printf("\rWaiting all subscribers exit with error: %s\n", err.Error())
+		fmt.Fprintf(t.Out, "\rWaiting all subscribers exit with error: %s\n", err.Error())
 
 		return nil, err
 	}
@@ -497,10 +659,44 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		// a really long time
 		duration = 1000 * time.Hour
 	}
-	fmt.Printf("\rFinished connecting to room, waiting %s                   \n", duration.String())
+	if params.WarmUp > 0 {
+		fmt.Fprintf(t.Out, "\rFinished connecting to room, warming up for %s, then waiting %s                   \n", params.WarmUp, duration)
+	} else {
+		fmt.Fprintf(t.Out, "\rFinished connecting to room, waiting %s                   \n", duration.String())
+	}
 	close(ready)
 
-	runWaiting(done, "Waiting when test will be finished")
+	// every tester of the test, replacements joining through churn are added once they are connected
+	initial := append(append([]*LoadTester{}, publishers...), testers...)
+	allTesters := func() []*LoadTester {
+		if churn == nil {
+			return initial
+		}
+		all := append(append([]*LoadTester{}, initial...), churn.replacedPublishers()...)
+		return append(all, churn.replacedSubscribers()...)
+	}
+
+	var dash *dashboard
+	if params.Dashboard {
+		dash = newDashboard(t.Out, &errs)
+		dash.Start(allTesters)
+	} else {
+		runWaiting(t.Out, done, "Waiting when test will be finished")
+	}
+
+	if churn != nil {
+		churn.Start()
+	}
+
+	var warmUp *phaseStats
+	if params.WarmUp > 0 {
+		select {
+		case <-ctx.Done():
+			// canceled, stats are reported as they are
+		case <-time.After(params.WarmUp):
+			warmUp = warmUpStats(params, allTesters(), &errs)
+		}
+	}
 
 	select {
 	case <-ctx.Done():
@@ -510,14 +706,145 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 	}
 
 	close(done)
+	if dash != nil {
+		dash.Stop()
+	}
+
+	if churn != nil {
+		// replacements are stopped and reported like the original testers
+		churn.Stop()
+		testers = append(testers, churn.replacedSubscribers()...)
+		publishers = append(publishers, churn.replacedPublishers()...)
+		churn.printSummary()
+	}
 
 	if speakerSim != nil {
 		speakerSim.Stop()
 	}
 
-	stats := make(map[string]map[string]*testerStats)
 	for _, t := range testers {
 		t.Stop()
+	}
+	for _, p := range publishers {
+		p.Stop()
+	}
+
+	// publishers that subscribe are reported with the subscribers too
+	receivers := testers
+	if params.PublishersSubscribe {
+		receivers = append(append([]*LoadTester{}, testers...), publishers...)
+	}
+
+	result := &testResult{
+		stats:      collectStats(receivers, &errs),
+		rooms:      roomNames(subParams),
+		publishers: collectStats(publishers, &errs),
+		clock:      clock,
+	}
+	if warmUp != nil && params.ReportWarmUp {
+		result.phases = []*phaseStats{warmUp}
+	}
+
+	return result, nil
+}
+
+// warmUpStats ends the warm-up by resetting the stats of testers, returning what they measured until now
+func warmUpStats(params Params, testers []*LoadTester, errs *syncmap.Map) *phaseStats {
+	ps := &phaseStats{
+		name:        "warm-up",
+		duration:    params.WarmUp,
+		subscribers: params.Subscribers,
+	}
+	if params.ReportWarmUp {
+		var subscribers []*LoadTester
+		for _, t := range testers {
+			if t.params.Subscribe {
+				subscribers = append(subscribers, t)
+			}
+		}
+		ps.stats = collectStats(subscribers, errs)
+	}
+
+	for _, t := range testers {
+		t.Reset()
+	}
+
+	return ps
+}
+
+// newSubscriber prepares a subscriber for the room described by subParam, seq identifies it within the room.
+// nil is returned when the room's publisher failed and the subscriber would have nothing to receive.
+func newSubscriber(params Params, subParam *trackParams, seq int, quality livekit.VideoQuality, errs *syncmap.Map) *LoadTester {
+	testerSubParams := params.TesterParams
+	testerSubParams.Sequence = seq
+	testerSubParams.Subscribe = true
+	testerSubParams.Resolution = subParam.resolution
+	testerSubParams.SameRoom = params.SameRoom
+	testerSubParams.IdentityPrefix += fmt.Sprintf("_sub%s", subParam.roomName)
+	testerSubParams.Room = subParam.roomName
+	testerSubParams.name = fmt.Sprintf("Sub %d in %s", seq, subParam.roomName)
+	if params.DataPublishers > 0 {
+		testerSubParams.dataBitrate = params.DataBitrate
+	}
+	testerSubParams.interval = params.Interval
+	if params.Synthetic {
+		testerSubParams.syntheticBitrate = params.SyntheticBitrate
+	}
+	if subParam.err != nil {
+		errs.Store(testerSubParams.name, subParam.err)
+		if !params.SameRoom {
+			return nil
+		}
+	}
+
+	return NewLoadTester(testerSubParams, quality)
+}
+
+// startSubscriber connects the subscriber, optionally publishing data once ready is closed
+func startSubscriber(params Params, tester *LoadTester, publishData bool, ready chan struct{}, errs *syncmap.Map) {
+	if err := tester.Start(); err != nil {
+		fmt.Fprintln(tester.params.out, errors.Wrapf(err, "could not connect %s", tester.params.name))
+		errs.Store(tester.params.name, err)
+		return
+	}
+
+	if !publishData {
+		return
+	}
+
+	if err := tester.PublishData(
+		params.DataPacketByteSize, params.DataBitrate, livekit.DataPacket_RELIABLE, ready); err != nil {
+		errs.Store(tester.params.name, err)
+	}
+}
+
+// viewerQuality assigns the first subscribers of a room to high quality, then medium, then low
+func viewerQuality(params Params, seq int) livekit.VideoQuality {
+	switch {
+	case seq < params.HighQualityViewer:
+		return livekit.VideoQuality_HIGH
+	case seq < params.HighQualityViewer+params.MediumQualityView:
+		return livekit.VideoQuality_MEDIUM
+	case seq < params.HighQualityViewer+params.MediumQualityView+params.LowQualityViewer:
+		return livekit.VideoQuality_LOW
+	default:
+		return livekit.VideoQuality_HIGH
+	}
+}
+
+func roomNames(subParams []*trackParams) []string {
+	rooms := make([]string, 0, len(subParams))
+	for _, p := range subParams {
+		rooms = append(rooms, p.roomName)
+	}
+
+	return rooms
+}
+
+// collectStats groups a snapshot of testers' stats by room and tester name
+func collectStats(testers []*LoadTester, errs *syncmap.Map) map[string]map[string]*testerStats {
+	stats := make(map[string]map[string]*testerStats)
+	for _, t := range testers {
 		if stats[t.params.Room] == nil {
 			stats[t.params.Room] = make(map[string]*testerStats)
 		}
@@ -527,7 +854,7 @@ func (t *LoadTest) run(ctx context.Context, params Params) (map[string]map[strin
 		}
 	}
 
-	return stats, nil
+	return stats
 }
 
 func startAudioPublishing(params Params, tester *LoadTester) error {
@@ -543,6 +870,36 @@ func startAudioPublishing(params Params, tester *LoadTester) error {
 	return nil
 }
 
+// startPublisher connects a publisher and publishes its tracks, the tester is returned even when that fails
+func startPublisher(params Params, testerPubParams TesterParams, resolution, codec string) (*LoadTester, error) {
+	testerVideo := NewLoadTester(testerPubParams, livekit.VideoQuality_HIGH)
+
+	if err := testerVideo.Start(); err != nil {
+		fmt.Fprintln(testerVideo.params.out, errors.Wrapf(err, "could not connect %s", testerPubParams.name))
+		return testerVideo, err
+	}
+
+	var err error
+	if params.Synthetic {
+		_, err = testerVideo.PublishSyntheticTrack(params.SyntheticBitrate, params.SyntheticFPS)
+	} else if params.Simulcast {
+		_, err = testerVideo.PublishSimulcastTrack("video-simulcast", resolution, codec)
+	} else {
+		_, err = testerVideo.PublishVideoTrack("video", resolution, codec)
+	}
+	if err != nil {
+		return testerVideo, err
+	}
+
+	if params.WithAudio {
+		if _, err = testerVideo.PublishAudioTrack("audio"); err != nil {
+			return testerVideo, err
+		}
+	}
+
+	return testerVideo, nil
+}
+
 func prepareTesterPubParams(params Params, seqNumber int, room string, roomID int) TesterParams {
 	testerPubParams := params.TesterParams
 	testerPubParams.Sequence = seqNumber
@@ -550,11 +907,18 @@ func prepareTesterPubParams(params Params, seqNumber int, room string, roomID in
 	testerPubParams.name = fmt.Sprintf("Pub %d", roomID)
 	testerPubParams.Room = room
 	testerPubParams.SameRoom = params.SameRoom
+	if params.PublishersSubscribe {
+		testerPubParams.Subscribe = true
+		testerPubParams.interval = params.Interval
+		if params.Synthetic {
+			testerPubParams.syntheticBitrate = params.SyntheticBitrate
+		}
+	}
 
 	return testerPubParams
 }
 
-func runWaiting(done chan struct{}, msg string) {
+func runWaiting(out io.Writer, done chan struct{}, msg string) {
 	go func() {
 		for {
 			select {
@@ -562,7 +926,7 @@ func runWaiting(done chan struct{}, msg string) {
 				return
 			default:
 				for _, r := range `-\|/` {
-					fmt.Printf("\r%s %c", msg, r)
+					fmt.Fprintf(out, "\r%s %c", msg, r)
 					time.Sleep(100 * time.Millisecond)
 				}
 			}
@@ -0,0 +1,57 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/syncmap"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestWarmUpStats(t *testing.T) {
+	pub := NewLoadTester(TesterParams{Room: "room", name: "Pub 1"}, livekit.VideoQuality_HIGH)
+	sub := NewLoadTester(TesterParams{Room: "room", name: "Sub 0 in room", Subscribe: true}, livekit.VideoQuality_HIGH)
+	s := &trackStats{trackID: "TR_1", kind: TrackKindVideo}
+	s.startedAt.Store(time.Now().Add(-30 * time.Second))
+	s.packets.Store(100)
+	s.dropped.Store(10)
+	s.recordLatency(int64(time.Second))
+	sub.stats.Store(s.trackID, s)
+
+	params := Params{Subscribers: 1, WarmUp: 30 * time.Second, ReportWarmUp: true}
+	ps := warmUpStats(params, []*LoadTester{pub, sub}, &syncmap.Map{})
+	require.Equal(t, "warm-up", ps.name)
+	require.Len(t, ps.stats["room"], 1)
+	require.Equal(t, int64(100), ps.stats["room"]["Sub 0 in room"].stats["TR_1"].packets.Load())
+
+	// the steady state starts from zero
+	require.Zero(t, s.packets.Load())
+	require.Zero(t, s.dropped.Load())
+	require.Zero(t, s.latencyHist.count.Load())
+	require.WithinDuration(t, time.Now(), s.startedAt.Load(), time.Second)
+}
+
+func TestGetCodecs(t *testing.T) {
+	test := NewLoadTest(Params{VideoPublishers: 3})
+	require.Equal(t, []string{"", "", ""}, test.GetCodecs())
+
+	test = NewLoadTest(Params{VideoPublishers: 3, VideoCodec: "vp8 h264"})
+	require.Equal(t, []string{"vp8", "h264", "vp8"}, test.GetCodecs())
+
+	for mime, expected := range map[string]rtp.Depacketizer{
+		"video/H264": &codecs.H264Packet{},
+		"video/VP8":  &codecs.VP8Packet{},
+		"video/VP9":  &codecs.VP9Packet{},
+		"audio/opus": &codecs.OpusPacket{},
+	} {
+		dpkt, err := depacketizerFor(mime)
+		require.NoError(t, err)
+		require.IsType(t, expected, dpkt)
+	}
+	_, err := depacketizerFor("video/H265")
+	require.Error(t, err)
+}
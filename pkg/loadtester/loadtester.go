@@ -3,6 +3,8 @@ package loadtester
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +31,18 @@ type LoadTester struct {
 	quality        livekit.VideoQuality
 	dataPublishing atomic.Bool
 	stats          *sync.Map
+	// what the tester sends on the tracks it publishes, track ID and layer => *publishedStats
+	published *sync.Map
+	layout    layoutState
+	// time it took to join the room, including retries
+	joinDuration atomic.Duration
+	joinAttempts atomic.Int32
+	join         joinProgress
+	// false while the connection is being resumed
+	connected  atomic.Bool
+	reconnects atomic.Int32
+	// closed on Stop to end interval recording, guarded by lock
+	intervalsDone chan struct{}
 }
 
 type TesterParams struct {
@@ -39,20 +53,38 @@ type TesterParams struct {
 	IdentityPrefix string
 	Resolution     string
 	SameRoom       bool
+	// how subscribers in a shared room render it, see Layout
+	Layout Layout
 	// true to subscribe to all published tracks
 	Subscribe bool
 
 	name           string
 	Sequence       int
 	expectedTracks int
+	// bitrate data publishers send at, in bps
+	dataBitrate int
+	// bitrate synthetic publishers send at, in bps
+	syntheticBitrate int
+	// tracks are measured per interval when set
+	interval time.Duration
+	// progress is printed here, stdout when nil
+	out io.Writer
 }
 
 func NewLoadTester(params TesterParams, quality livekit.VideoQuality) *LoadTester {
+	if params.out == nil {
+		params.out = os.Stdout
+	}
 	return &LoadTester{
 		params:         params,
 		quality:        quality,
 		stats:          &sync.Map{},
+		published:      &sync.Map{},
 		trackQualities: make(map[string]livekit.VideoQuality),
+		layout: layoutState{
+			videoPubs: make(map[string]*lksdk.RemoteTrackPublication),
+			tiles:     make(map[string]struct{}),
+		},
 	}
 }
 
@@ -65,21 +97,35 @@ func (t *LoadTester) Start() error {
 	participantCallback := lksdk.ParticipantCallback{
 		OnTrackSubscribed: t.onTrackSubscribed,
 		OnTrackSubscriptionFailed: func(sid string, rp *lksdk.RemoteParticipant) {
-			fmt.Printf("track subscription failed, lp:%v, sid:%v, rp:%v/%v\n", identity, sid, rp.Identity(), rp.SID())
+			fmt.Fprintf(t.params.out, "track subscription failed, lp:%v, sid:%v, rp:%v/%v\n", identity, sid, rp.Identity(), rp.SID())
 		},
 	}
 
-	if !strings.HasPrefix(t.params.name, "Pub") {
+	if t.params.Subscribe || !strings.HasPrefix(t.params.name, "Pub") {
 		participantCallback.OnDataReceived = t.onDataReceived
 		participantCallback.OnTrackPublished = t.onTrackPublished
+		participantCallback.OnTrackUnpublished = t.onLayoutTrackUnpublished
 	}
 
 	t.room = lksdk.CreateRoom(&lksdk.RoomCallback{
-		ParticipantCallback: participantCallback,
+		ParticipantCallback:     participantCallback,
+		OnActiveSpeakersChanged: t.onActiveSpeakersChanged,
+		OnReconnecting: func() {
+			t.connected.Store(false)
+			t.reconnects.Inc()
+		},
+		OnReconnected: func() {
+			t.connected.Store(true)
+		},
+		OnDisconnected: func() {
+			t.connected.Store(false)
+		},
 	})
 	var err error
+	t.join.start.Store(time.Now())
 	// make up to 10 reconnect attempts
 	for i := 0; i < 10; i++ {
+		t.joinAttempts.Inc()
 		err = t.room.Join(t.params.URL, lksdk.ConnectInfo{
 			APIKey:              t.params.APIKey,
 			APISecret:           t.params.APISecret,
@@ -94,8 +140,16 @@ func (t *LoadTester) Start() error {
 	if err != nil {
 		return err
 	}
+	t.joinDuration.Store(time.Since(t.join.start.Load()))
 
+	if t.params.interval > 0 {
+		t.lock.Lock()
+		t.intervalsDone = make(chan struct{})
+		go t.recordIntervals(t.intervalsDone)
+		t.lock.Unlock()
+	}
 	t.running.Store(true)
+	t.connected.Store(true)
 	for _, p := range t.room.GetParticipants() {
 		for _, pub := range p.Tracks() {
 			if remotePub, ok := pub.(*lksdk.RemoteTrackPublication); ok {
@@ -111,23 +165,26 @@ func (t *LoadTester) IsRunning() bool {
 	return t.running.Load()
 }
 
+// IsConnected is true while the tester is in its room and not reconnecting
+func (t *LoadTester) IsConnected() bool {
+	return t.IsRunning() && t.connected.Load()
+}
+
 func (t *LoadTester) PublishAudioTrack(name string) (string, error) {
 	if !t.IsRunning() {
 		return "", nil
 	}
 
-	fmt.Println("publishing audio track -", t.room.LocalParticipant.Identity())
+	fmt.Fprintln(t.params.out, "publishing audio track -", t.room.LocalParticipant.Identity())
 	audioLooper, err := provider2.CreateAudioLooper()
 	if err != nil {
 		return "", err
 	}
-	track, err := lksdk.NewLocalSampleTrack(audioLooper.Codec())
+	stats := newPublishedStats(TrackKindAudio, nil)
+	track, err := newSampleTrack(audioLooper, audioLooper.Codec(), stats)
 	if err != nil {
 		return "", err
 	}
-	if err := track.StartWrite(audioLooper, nil); err != nil {
-		return "", err
-	}
 
 	p, err := t.room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
 		Name: name,
@@ -135,6 +192,8 @@ func (t *LoadTester) PublishAudioTrack(name string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	t.addPublished(p.SID(), stats)
+	t.reached(&t.join.firstPublished)
 	return p.SID(), nil
 }
 
@@ -143,25 +202,81 @@ func (t *LoadTester) PublishVideoTrack(name, resolution, codec string) (string,
 		return "", nil
 	}
 
-	fmt.Println("publishing video track -", t.room.LocalParticipant.Identity())
+	fmt.Fprintln(t.params.out, "publishing video track -", t.room.LocalParticipant.Identity())
 	loopers, err := provider2.CreateVideoLoopers(resolution, codec, false)
 	if err != nil {
 		return "", err
 	}
-	track, err := lksdk.NewLocalSampleTrack(loopers[0].Codec())
+	if svc, ok := loopers[0].(provider2.SVCLooper); ok {
+		return t.publishSVCTrack(name, svc)
+	}
+	stats := newPublishedStats(TrackKindVideo, nil)
+	track, err := newSampleTrack(loopers[0], loopers[0].Codec(), stats)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := t.room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
+		Name: name,
+	})
 	if err != nil {
 		return "", err
 	}
-	if err := track.StartWrite(loopers[0], nil); err != nil {
+	t.addPublished(p.SID(), stats)
+	t.reached(&t.join.firstPublished)
+	return p.SID(), nil
+}
+
+// publishSVCTrack publishes the spatial layers of looper in one track. The SDK announces it
+// as a single layer of the size of the highest one, the SFU reads the layers from the packets.
+func (t *LoadTester) publishSVCTrack(name string, looper provider2.SVCLooper) (string, error) {
+	stats := newPublishedStats(TrackKindVideo, nil)
+	track, err := newRTPTrack(looper, looper.Codec(), looper.Payloader(), stats)
+	if err != nil {
 		return "", err
 	}
 
+	layer := looper.ToLayer()
 	p, err := t.room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
-		Name: name,
+		Name:        name,
+		Source:      livekit.TrackSource_CAMERA,
+		VideoWidth:  int(layer.Width),
+		VideoHeight: int(layer.Height),
+	})
+	if err != nil {
+		return "", err
+	}
+	t.addPublished(p.SID(), stats)
+	t.reached(&t.join.firstPublished)
+	return p.SID(), nil
+}
+
+// PublishSyntheticTrack publishes frames of a LoadTestProvider rather than video, see Params.Synthetic
+func (t *LoadTester) PublishSyntheticTrack(bitrate, fps int) (string, error) {
+	if !t.IsRunning() {
+		return "", nil
+	}
+
+	fmt.Fprintln(t.params.out, "publishing synthetic track -", t.room.LocalParticipant.Identity())
+	provider, err := NewLoadTestProvider(uint32(bitrate), uint32(fps))
+	if err != nil {
+		return "", err
+	}
+	stats := newPublishedStats(TrackKindVideo, nil)
+	track, err := newSampleTrack(provider, provider.Codec(), stats)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := t.room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{
+		Name:   syntheticTrackName,
+		Source: livekit.TrackSource_CAMERA,
 	})
 	if err != nil {
 		return "", err
 	}
+	t.addPublished(p.SID(), stats)
+	t.reached(&t.join.firstPublished)
 	return p.SID(), nil
 }
 
@@ -178,7 +293,7 @@ func (t *LoadTester) PublishData(packetSizeInByte, bitrate int, kind livekit.Dat
 
 	data := prepareData(packetSizeInByte)
 
-	fmt.Printf("\rpublishing data track - %s                   \n", t.room.LocalParticipant.Identity())
+	fmt.Fprintf(t.params.out, "\rpublishing data track - %s                   \n", t.room.LocalParticipant.Identity())
 
 	if err := t.room.LocalParticipant.PublishData([]byte("ensure connect"), kind, []string{"unexist"}); err != nil {
 		return err
@@ -203,7 +318,7 @@ func (t *LoadTester) PublishData(packetSizeInByte, bitrate int, kind livekit.Dat
 
 			err := t.room.LocalParticipant.PublishData(data, kind, nil)
 			if err != nil {
-				fmt.Println("error publishing data", err, "participant", t.room.LocalParticipant.Identity())
+				fmt.Fprintln(t.params.out, "error publishing data", err, "participant", t.room.LocalParticipant.Identity())
 			}
 		}
 	}()
@@ -212,25 +327,28 @@ func (t *LoadTester) PublishData(packetSizeInByte, bitrate int, kind livekit.Dat
 
 func (t *LoadTester) PublishSimulcastTrack(name, resolution, codec string) (string, error) {
 	var tracks []*lksdk.LocalSampleTrack
+	var layers []*publishedStats
 
-	fmt.Println("publishing simulcast video track -", t.room.LocalParticipant.Identity())
+	fmt.Fprintln(t.params.out, "publishing simulcast video track -", t.room.LocalParticipant.Identity())
 	loopers, err := provider2.CreateVideoLoopers(resolution, codec, true)
 	if err != nil {
 		return "", err
 	}
+	if svc, ok := loopers[0].(provider2.SVCLooper); ok {
+		return t.publishSVCTrack(name, svc)
+	}
 	// for video, publish three simulcast layers
 	for _, looper := range loopers {
 		layer := looper.ToLayer()
 
-		track, err := lksdk.NewLocalSampleTrack(looper.Codec(),
+		stats := newPublishedStats(TrackKindVideo, layer)
+		track, err := newSampleTrack(looper, looper.Codec(), stats,
 			lksdk.WithSimulcast("loadtest-video", layer))
 		if err != nil {
 			return "", err
 		}
-		if err := track.StartWrite(looper, nil); err != nil {
-			return "", err
-		}
 		tracks = append(tracks, track)
+		layers = append(layers, stats)
 	}
 
 	p, err := t.room.LocalParticipant.PublishSimulcastTrack(tracks, &lksdk.TrackPublicationOptions{
@@ -240,6 +358,8 @@ func (t *LoadTester) PublishSimulcastTrack(name, resolution, codec string) (stri
 	if err != nil {
 		return "", err
 	}
+	t.addPublished(p.SID(), layers...)
+	t.reached(&t.join.firstPublished)
 
 	return p.SID(), nil
 }
@@ -248,10 +368,14 @@ func (t *LoadTester) getStats() *testerStats {
 	stats := &testerStats{
 		expectedTracks: t.params.expectedTracks,
 		stats:          make(map[string]*trackStats),
+		joinDuration:   t.joinDuration.Load(),
+		joinAttempts:   int(t.joinAttempts.Load()),
+		milestones:     t.joinMilestones(),
+		published:      t.publishedResults(),
 	}
 
 	t.stats.Range(func(key, value interface{}) bool {
-		stats.stats[key.(string)] = value.(*trackStats)
+		stats.stats[key.(string)] = value.(*trackStats).snapshot()
 		return true
 	})
 
@@ -259,27 +383,53 @@ func (t *LoadTester) getStats() *testerStats {
 }
 
 func (t *LoadTester) Reset() {
-	stats := sync.Map{}
 	t.stats.Range(func(key, value interface{}) bool {
-		old := value.(*trackStats)
-		stats.Store(key, &trackStats{
-			trackID: old.trackID,
-		})
+		value.(*trackStats).reset()
+		return true
+	})
+	t.published.Range(func(key, value interface{}) bool {
+		value.(*publishedStats).reset()
 		return true
 	})
-
-	t.stats = &stats
 }
 
 func (t *LoadTester) Stop() {
-	if !t.IsRunning() {
+	// only the first of concurrent calls stops the tester
+	if !t.running.CompareAndSwap(true, false) {
 		return
 	}
-	t.running.Store(false)
+	t.connected.Store(false)
 	t.room.Disconnect()
+
+	t.lock.Lock()
+	if t.intervalsDone != nil {
+		close(t.intervalsDone)
+		t.intervalsDone = nil
+	}
+	t.lock.Unlock()
+
+	now := time.Now()
+	t.stats.Range(func(key, value interface{}) bool {
+		s := value.(*trackStats)
+		s.endedAt.Store(now)
+		if t.params.interval > 0 {
+			// the last interval is partial
+			s.closeInterval(now)
+		}
+		return true
+	})
+	t.published.Range(func(key, value interface{}) bool {
+		value.(*publishedStats).endedAt.Store(now)
+		return true
+	})
 }
 
 func (t *LoadTester) onTrackPublished(publication *lksdk.RemoteTrackPublication, rp *lksdk.RemoteParticipant) {
+	if t.usesLayout() {
+		t.subscribeWithLayout(publication)
+		return
+	}
+
 	publication.SetSubscribed(true)
 }
 
@@ -288,29 +438,76 @@ func (t *LoadTester) onTrackSubscribed(track *webrtc.TrackRemote, pub *lksdk.Rem
 		trackID: track.ID(),
 		kind:    TrackKind(pub.Kind()),
 	}
+	if pub.Name() == syntheticTrackName {
+		s.expectedBitrate.Store(int64(t.params.syntheticBitrate))
+	} else if s.kind == TrackKindVideo {
+		s.expectedBitrate.Store(t.expectedVideoBitrate(pub))
+		if !t.usesLayout() {
+			s.requested = strings.ToLower(t.requestedQuality().String())
+		}
+	}
 
 	t.stats.Store(track.ID(), s)
+	t.reached(&t.join.firstSubscribed)
 
-	fmt.Printf("\rsubscribed to track %s %s %s                   \n", t.room.LocalParticipant.Identity(), pub.SID(), pub.Kind())
+	fmt.Fprintf(t.params.out, "\rsubscribed to track %s %s %s                   \n", t.room.LocalParticipant.Identity(), pub.SID(), pub.Kind())
 
 	go t.consumeTrack(track, pub, rp)
 
+	if t.usesLayout() && s.kind == TrackKindVideo {
+		t.applyLayout(pub, rp)
+		return
+	}
+
 	if !t.params.SameRoom && s.kind == TrackKindVideo {
 		resolutions := provider2.GetVideoResolution(t.params.Resolution)
-		if resolutions == nil || len(resolutions) != 3 {
-			fmt.Printf("invalid resolution %s\n", t.params.Resolution)
+		if len(resolutions) == 0 {
+			fmt.Fprintf(t.params.out, "invalid resolution %s\n", t.params.Resolution)
 			return
 		}
 
-		switch t.quality {
-		case livekit.VideoQuality_HIGH:
-			pub.SetVideoDimensions(uint32(resolutions[0].Width), uint32(resolutions[0].Height))
-		case livekit.VideoQuality_MEDIUM:
-			pub.SetVideoDimensions(uint32(resolutions[1].Width), uint32(resolutions[1].Height))
-		case livekit.VideoQuality_LOW:
-			pub.SetVideoDimensions(uint32(resolutions[2].Width), uint32(resolutions[2].Height))
+		r := ratioFor(resolutions, t.quality)
+		pub.SetVideoDimensions(uint32(r.Width), uint32(r.Height))
+	}
+}
+
+// ratioFor returns the layer of quality, or the lowest layer when ladders have fewer than three
+func ratioFor(resolutions []provider2.Ratio, quality livekit.VideoQuality) provider2.Ratio {
+	for _, r := range resolutions {
+		if r.Quality == quality {
+			return r
+		}
+	}
+	return resolutions[len(resolutions)-1]
+}
+
+// requestedQuality is the video quality this tester asks for, subscribers in the publishers' room take the highest
+func (t *LoadTester) requestedQuality() livekit.VideoQuality {
+	if t.params.SameRoom {
+		return livekit.VideoQuality_HIGH
+	}
+	return t.quality
+}
+
+// expectedVideoBitrate is the bitrate of the layer this tester asks for, zero when that is not known
+func (t *LoadTester) expectedVideoBitrate(pub *lksdk.RemoteTrackPublication) int64 {
+	if t.usesLayout() {
+		// dimensions follow the layout and change during the test
+		return 0
+	}
+
+	quality := t.requestedQuality()
+	layers := pub.TrackInfo().GetLayers()
+	for _, l := range layers {
+		if l.Quality == quality {
+			return int64(l.Bitrate)
 		}
 	}
+	if len(layers) == 1 {
+		return int64(layers[0].Bitrate)
+	}
+
+	return 0
 }
 
 func (t *LoadTester) consumeTrack(track *webrtc.TrackRemote, pub *lksdk.RemoteTrackPublication, rp *lksdk.RemoteParticipant) {
@@ -323,25 +520,34 @@ func (t *LoadTester) consumeTrack(track *webrtc.TrackRemote, pub *lksdk.RemoteTr
 	}()
 
 	var dpkt rtp.Depacketizer
-	isVideo := false
-	if pub.Kind() == lksdk.TrackKindVideo {
-		dpkt = &codecs.H264Packet{}
-		isVideo = true
-	} else {
-		dpkt = &codecs.OpusPacket{}
+	var err error
+	synthetic := pub.Name() == syntheticTrackName
+	if synthetic {
+		dpkt = &LoadTestDepacketizer{}
+	} else if dpkt, err = depacketizerFor(track.Codec().MimeType); err != nil {
+		fmt.Fprintf(t.params.out, "cannot read track %s: %s\n", track.ID(), err)
+		return
 	}
+	isVideo := pub.Kind() == lksdk.TrackKindVideo
+	mimeType := track.Codec().MimeType
 
 	value, ok := t.stats.Load(track.ID())
 	if !ok {
-		fmt.Println("invalid stats")
+		fmt.Fprintln(t.params.out, "invalid stats")
 		return
 	}
 
 	stats := value.(*trackStats)
 
+	// kept on the stats, like the sequence tracker below
+	if isVideo && stats.frameTracker == nil {
+		stats.frameTracker = newFrameTracker(mimeType, synthetic, time.Now())
+	}
+
 	sb := samplebuilder.New(100, dpkt, track.Codec().ClockRate, samplebuilder.WithPacketDroppedHandler(func() {
 		stats.dropped.Inc()
 		if isVideo {
+			stats.frameTracker.lost()
 			rp.WritePLI(track.SSRC())
 		}
 	}))
@@ -351,6 +557,11 @@ func (t *LoadTester) consumeTrack(track *webrtc.TrackRemote, pub *lksdk.RemoteTr
 		stats.startedAt.Store(time.Now())
 	}
 
+	// kept on the stats, so that sequence numbers carry over when reading restarts
+	if stats.sequence == nil {
+		stats.sequence = newSequenceTracker(track.Codec().ClockRate)
+	}
+
 	for {
 		pkt, _, err := track.ReadRTP()
 		if err != nil {
@@ -359,26 +570,37 @@ func (t *LoadTester) consumeTrack(track *webrtc.TrackRemote, pub *lksdk.RemoteTr
 		if pkt == nil {
 			continue
 		}
+		now := time.Now()
+		t.reached(&t.join.firstRTP)
+		stats.recordArrival(now)
+		stats.sequence.push(pkt, now, stats)
+		if l, ok := layerOf(mimeType, pkt.Payload); ok {
+			stats.recordLayer(l)
+		}
 		sb.Push(pkt)
 
-		for _, pkt := range sb.PopPackets() {
-			stats.bytes.Add(int64(len(pkt.Payload)))
-			stats.packets.Inc()
-
-			if len(pkt.Payload) > 8 {
-				sentAt := int64(binary.LittleEndian.Uint64(pkt.Payload[len(pkt.Payload)-8:]))
-				latency := time.Now().UnixNano() - sentAt
-				sentTime := time.Unix(0, sentAt)
+		// every pop is one frame, a packet may complete several
+		for packets := sb.PopPackets(); packets != nil; packets = sb.PopPackets() {
+			if isVideo {
+				stats.frameTracker.push(packets, now, stats)
+				if stats.firstKeyframe.Load() != 0 {
+					t.reached(&t.join.firstKeyframe)
+				}
+				if width, height, ok := frameDimensions(mimeType, packets); ok {
+					if quality, ok := qualityForDimensions(pub.TrackInfo().GetLayers(), width, height); ok {
+						stats.recordDelivered(quality, now, stats.frameTracker.subscribedAt)
+					}
+				}
+			}
 
-				// Define a reasonable time range for validation
-				minTime := time.Now().Add(-20 * time.Minute)
-				maxTime := time.Now().Add(20 * time.Minute)
+			for _, pkt := range packets {
+				stats.bytes.Add(int64(len(pkt.Payload)))
+				stats.packets.Inc()
 
-				// Check if sentTime is within the valid range
-				if sentTime.After(minTime) && sentTime.Before(maxTime) {
-					if latency > 0 {
-						stats.latency.Add(latency)
-						stats.latencyCount.Inc()
+				// only synthetic frames carry a send time, see syntheticSendTime
+				if synthetic {
+					if sentTime, ok := syntheticSendTime(pkt); ok {
+						stats.recordSendTime(sentTime, provider2.Now())
 					}
 				}
 			}
@@ -396,22 +618,36 @@ func (t *LoadTester) onDataReceived(data []byte, rp *lksdk.RemoteParticipant) {
 			kind:    TrackKindData,
 		}
 
+		s.expectedBitrate.Store(int64(t.params.dataBitrate))
 		s.startedAt.Store(time.Now())
 		t.stats.Store(rp.SID(), s)
 	} else {
 		s = value.(*trackStats)
 	}
 
+	s.recordArrival(time.Now())
 	s.bytes.Add(int64(len(data)))
 	s.packets.Inc()
 	if len(data) > 8 {
 		// Extract the timestamp from the data
 		sentAt := int64(binary.LittleEndian.Uint64(data[len(data)-8:]))
+		s.recordSendTime(time.Unix(0, sentAt), provider2.Now())
+	}
+}
 
-		// Calculate the latency
-		latency := time.Now().UnixNano() - sentAt
-		s.latency.Add(latency)
-		s.latencyCount.Inc()
+// depacketizerFor returns the depacketizer for the negotiated codec of a track
+func depacketizerFor(mimeType string) (rtp.Depacketizer, error) {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return &codecs.H264Packet{}, nil
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return &codecs.VP8Packet{}, nil
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return &codecs.VP9Packet{}, nil
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return &codecs.OpusPacket{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported codec %s", mimeType)
 	}
 }
 
@@ -419,7 +655,7 @@ func prepareData(size int) []byte {
 	data := make([]byte, size)
 
 	ts := make([]byte, 8)
-	binary.LittleEndian.PutUint64(ts, uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(ts, uint64(provider2.Now().UnixNano()))
 
 	return append(data, ts...)
 }
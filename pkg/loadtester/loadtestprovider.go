@@ -3,10 +3,25 @@ package loadtester
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
+	"fmt"
 	"time"
 
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
+
+	"github.com/livekit/livekit-cli/pkg/provider"
+)
+
+// name of tracks published with a LoadTestProvider, subscribers read them with a LoadTestDepacketizer
+const syntheticTrackName = "synthetic"
+
+const (
+	defaultSyntheticBitrate = 2000 * 1024
+	defaultSyntheticFPS     = 30
+	// marker, two zero bytes and the send time
+	minSyntheticSample = 4 + 2 + 8
 )
 
 // LoadTestProvider is designed to be used with the load tester.
@@ -16,18 +31,36 @@ type LoadTestProvider struct {
 	SampleDuration time.Duration
 }
 
-func NewLoadTestProvider(bitrate uint32) (*LoadTestProvider, error) {
-	bytesPerSample := bitrate / 8 / 30
-	if bytesPerSample < 8 {
-		return nil, errors.New("bitrate lower than minimum of 1920")
+// NewLoadTestProvider returns a provider of fps samples per second adding up to bitrate in bps
+func NewLoadTestProvider(bitrate uint32, fps uint32) (*LoadTestProvider, error) {
+	if fps == 0 {
+		return nil, fmt.Errorf("frame rate must be positive")
+	}
+
+	bytesPerSample := bitrate / 8 / fps
+	if bytesPerSample < minSyntheticSample {
+		return nil, fmt.Errorf("bitrate lower than minimum of %d at %d fps", minSyntheticSample*8*fps, fps)
 	}
 
 	return &LoadTestProvider{
-		SampleDuration: time.Second / 30,
+		SampleDuration: time.Second / time.Duration(fps),
 		BytesPerSample: bytesPerSample,
 	}, nil
 }
 
+// Codec is VP8, as the SFU only forwards codecs it knows. Samples are packetized with a VP8 payload descriptor,
+// which LoadTestDepacketizer strips again, but they are not VP8 and nothing should try to decode them.
+func (p *LoadTestProvider) Codec() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{
+		MimeType:  webrtc.MimeTypeVP8,
+		ClockRate: 90000,
+		RTCPFeedback: []webrtc.RTCPFeedback{
+			{Type: webrtc.TypeRTCPFBNACK},
+			{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+		},
+	}
+}
+
 func (p *LoadTestProvider) NextSample() (media.Sample, error) {
 	// sample format:
 	// 0xfafafa + 0000... + 8 bytes for ts
@@ -37,7 +70,7 @@ func (p *LoadTestProvider) NextSample() (media.Sample, error) {
 	})
 	buf.Write(make([]byte, p.BytesPerSample-12))
 	ts := make([]byte, 8)
-	binary.LittleEndian.PutUint64(ts, uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(ts, uint64(provider.Now().UnixNano()))
 	buf.Write(ts)
 
 	return media.Sample{
@@ -46,6 +79,15 @@ func (p *LoadTestProvider) NextSample() (media.Sample, error) {
 	}, nil
 }
 
+// syntheticSendTime reads the send time a LoadTestProvider writes at the end of its samples.
+// Only the last packet of a sample, which carries the marker, ends in it.
+func syntheticSendTime(pkt *rtp.Packet) (time.Time, bool) {
+	if !pkt.Marker || len(pkt.Payload) <= 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.LittleEndian.Uint64(pkt.Payload[len(pkt.Payload)-8:]))), true
+}
+
 func (p *LoadTestProvider) OnBind() error {
 	return nil
 }
@@ -54,19 +96,22 @@ func (p *LoadTestProvider) OnUnbind() error {
 	return nil
 }
 
+// LoadTestDepacketizer reads the samples of a LoadTestProvider from packets with a VP8 payload descriptor
 type LoadTestDepacketizer struct {
+	vp8 codecs.VP8Packet
 }
 
 func (d *LoadTestDepacketizer) Unmarshal(packet []byte) ([]byte, error) {
-	return packet, nil
+	return d.vp8.Unmarshal(packet)
 }
 
 func (d *LoadTestDepacketizer) IsPartitionHead(payload []byte) bool {
-	if len(payload) < 4 {
+	sample, err := (&codecs.VP8Packet{}).Unmarshal(payload)
+	if err != nil || len(sample) < 4 {
 		return false
 	}
 	for i := 0; i < 4; i++ {
-		if payload[i] != 0xfa {
+		if sample[i] != 0xfa {
 			return false
 		}
 	}
@@ -74,6 +119,10 @@ func (d *LoadTestDepacketizer) IsPartitionHead(payload []byte) bool {
 }
 
 func (d *LoadTestDepacketizer) IsPartitionTail(marker bool, payload []byte) bool {
+	if marker {
+		return true
+	}
+
 	size := len(payload)
 	if size < 10 {
 		return false
@@ -85,5 +134,6 @@ func (d *LoadTestDepacketizer) IsPartitionTail(marker bool, payload []byte) bool
 	}
 	// parse timestamp
 	ts := binary.LittleEndian.Uint64(payload[size-8:])
-	return ts > uint64(time.Now().Add(-time.Minute).UnixNano()) && ts < uint64(time.Now().UnixNano())
+	now := provider.Now()
+	return ts > uint64(now.Add(-time.Minute).UnixNano()) && ts < uint64(now.UnixNano())
 }
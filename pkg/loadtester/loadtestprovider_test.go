@@ -0,0 +1,51 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTestProvider(t *testing.T) {
+	_, err := NewLoadTestProvider(3000, 30)
+	require.Error(t, err)
+
+	p, err := NewLoadTestProvider(240_000, 30)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1000), p.BytesPerSample)
+	require.Equal(t, time.Second/30, p.SampleDuration)
+
+	sample, err := p.NextSample()
+	require.NoError(t, err)
+	require.Len(t, sample.Data, 1000)
+
+	// sent the way the SDK does for VP8, in packets of at most 300 bytes
+	payloader := &codecs.VP8Payloader{EnablePictureID: true}
+	payloads := payloader.Payload(300, sample.Data)
+	require.Greater(t, len(payloads), 1)
+
+	d := &LoadTestDepacketizer{}
+	var frame []byte
+	for i, payload := range payloads {
+		require.Equal(t, i == 0, d.IsPartitionHead(payload))
+		require.Equal(t, i == len(payloads)-1, d.IsPartitionTail(i == len(payloads)-1, payload))
+
+		data, err := d.Unmarshal(payload)
+		require.NoError(t, err)
+		frame = append(frame, data...)
+	}
+	require.Equal(t, sample.Data, frame)
+
+	// only the marked last packet carries the send time
+	for i, payload := range payloads {
+		last := i == len(payloads)-1
+		sentAt, ok := syntheticSendTime(&rtp.Packet{Header: rtp.Header{Marker: last}, Payload: payload})
+		require.Equal(t, last, ok)
+		if last {
+			require.WithinDuration(t, time.Now(), sentAt, time.Second)
+		}
+	}
+}
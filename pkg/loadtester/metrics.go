@@ -0,0 +1,208 @@
+package loadtester
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/syncmap"
+)
+
+const metricsPath = "/metrics"
+
+// upper bounds of the exported latency histogram, 1ms to ~16s
+var metricsLatencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 15)
+
+var (
+	packetsDesc = prometheus.NewDesc("livekit_loadtest_packets_total",
+		"Packets received by subscribers", []string{"room", "kind", "quality"}, nil)
+	bytesDesc = prometheus.NewDesc("livekit_loadtest_bytes_total",
+		"Bytes received by subscribers", []string{"room", "kind", "quality"}, nil)
+	droppedDesc = prometheus.NewDesc("livekit_loadtest_dropped_packets_total",
+		"Packets dropped by subscribers' sample builders", []string{"room", "kind", "quality"}, nil)
+	latencyDesc = prometheus.NewDesc("livekit_loadtest_latency_seconds",
+		"Latency from publisher to subscriber", []string{"room", "kind", "quality"}, nil)
+	connectedDesc = prometheus.NewDesc("livekit_loadtest_testers_connected",
+		"Testers that are in their room and not reconnecting", []string{"room"}, nil)
+	joinFailuresDesc = prometheus.NewDesc("livekit_loadtest_join_failures_total",
+		"Testers that could not join their room", []string{"room"}, nil)
+	reconnectsDesc = prometheus.NewDesc("livekit_loadtest_reconnects_total",
+		"Times testers lost their connection and resumed it", []string{"room"}, nil)
+)
+
+// metricsCollector exposes the stats of every tester of the running test to Prometheus.
+// Stats are read from the testers on every scrape, like the dashboard does.
+type metricsCollector struct {
+	lock    sync.Mutex
+	testers []metricsTester
+}
+
+type metricsTester struct {
+	tester *LoadTester
+	// where errors of the tester are recorded
+	errs *syncmap.Map
+}
+
+type metricsKey struct {
+	room    string
+	kind    TrackKind
+	quality string
+}
+
+type metricsTrack struct {
+	packets, bytes, dropped int64
+	latencySum              int64
+	latency                 latencyHistogram
+}
+
+type metricsRoom struct {
+	connected, joinFailures, reconnects int
+}
+
+func newMetricsCollector() *metricsCollector {
+	return &metricsCollector{}
+}
+
+// add makes testers show up in metrics, it does nothing when metrics are disabled
+func (m *metricsCollector) add(errs *syncmap.Map, testers ...*LoadTester) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, t := range testers {
+		if t != nil {
+			m.testers = append(m.testers, metricsTester{tester: t, errs: errs})
+		}
+	}
+}
+
+func (m *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		packetsDesc, bytesDesc, droppedDesc, latencyDesc, connectedDesc, joinFailuresDesc, reconnectsDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (m *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	m.lock.Lock()
+	testers := append([]metricsTester{}, m.testers...)
+	m.lock.Unlock()
+
+	tracks := make(map[metricsKey]*metricsTrack)
+	rooms := make(map[string]*metricsRoom)
+	for _, mt := range testers {
+		t := mt.tester
+		room := rooms[t.params.Room]
+		if room == nil {
+			room = &metricsRoom{}
+			rooms[t.params.Room] = room
+		}
+
+		if t.IsConnected() {
+			room.connected++
+		}
+		room.reconnects += int(t.reconnects.Load())
+		if e, _ := mt.errs.Load(t.params.name); e != nil && t.joinDuration.Load() == 0 {
+			room.joinFailures++
+		}
+
+		t.stats.Range(func(_, value interface{}) bool {
+			s := value.(*trackStats)
+			key := metricsKey{room: t.params.Room, kind: s.kind}
+			if s.kind == TrackKindVideo {
+				key.quality = strings.ToLower(t.quality.String())
+			}
+			track := tracks[key]
+			if track == nil {
+				track = &metricsTrack{}
+				tracks[key] = track
+			}
+
+			track.packets += s.packets.Load()
+			track.bytes += s.bytes.Load()
+			track.dropped += s.dropped.Load()
+			track.latencySum += s.latency.Load()
+			track.latency.merge(&s.latencyHist)
+			return true
+		})
+	}
+
+	for key, track := range tracks {
+		labels := []string{key.room, string(key.kind), key.quality}
+		ch <- prometheus.MustNewConstMetric(packetsDesc, prometheus.CounterValue, float64(track.packets), labels...)
+		ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.CounterValue, float64(track.bytes), labels...)
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(track.dropped), labels...)
+		count, buckets := track.latency.cumulative(metricsLatencyBuckets)
+		ch <- prometheus.MustNewConstHistogram(latencyDesc, count,
+			time.Duration(track.latencySum).Seconds(), buckets, labels...)
+	}
+
+	for name, room := range rooms {
+		ch <- prometheus.MustNewConstMetric(connectedDesc, prometheus.GaugeValue, float64(room.connected), name)
+		ch <- prometheus.MustNewConstMetric(joinFailuresDesc, prometheus.CounterValue, float64(room.joinFailures), name)
+		ch <- prometheus.MustNewConstMetric(reconnectsDesc, prometheus.CounterValue, float64(room.reconnects), name)
+	}
+}
+
+// cumulative returns the count and the cumulative counts at the given ascending upper bounds in seconds.
+// Latencies are counted at the first bound at or above their bucket's upper bound.
+func (h *latencyHistogram) cumulative(bounds []float64) (uint64, map[float64]uint64) {
+	counts := make(map[float64]uint64, len(bounds))
+	var seen uint64
+	next := 0
+	for i := range h.buckets {
+		upper := bucketUpperBound(i).Seconds()
+		for next < len(bounds) && bounds[next] < upper {
+			counts[bounds[next]] = seen
+			next++
+		}
+		seen += uint64(h.buckets[i].Load())
+	}
+	for ; next < len(bounds); next++ {
+		counts[bounds[next]] = seen
+	}
+
+	return seen, counts
+}
+
+// serveMetrics serves the Prometheus metrics of testers added to m, and Go runtime metrics, on addr.
+// The returned function stops the server.
+func serveMetrics(addr string, m *metricsCollector, out io.Writer) (func(), error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(m); err != nil {
+		return nil, err
+	}
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not serve metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	fmt.Fprintf(out, "Serving metrics on http://%s%s\n", listener.Addr(), metricsPath)
+
+	return func() {
+		_ = server.Close()
+	}, nil
+}
@@ -0,0 +1,73 @@
+package loadtester
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/syncmap"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	errs := &syncmap.Map{}
+	sub := NewLoadTester(TesterParams{Room: "room", name: "Sub 0 in room"}, livekit.VideoQuality_LOW)
+	failed := NewLoadTester(TesterParams{Room: "room", name: "Sub 1 in room"}, livekit.VideoQuality_HIGH)
+	errs.Store("Sub 1 in room", errors.New("could not connect"))
+
+	s := &trackStats{trackID: "TR_video", kind: TrackKindVideo}
+	s.packets.Store(100)
+	s.bytes.Store(125000)
+	s.dropped.Store(2)
+	for _, latency := range []time.Duration{3 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond} {
+		s.recordLatency(int64(latency))
+	}
+	sub.stats.Store(s.trackID, s)
+	sub.reconnects.Inc()
+
+	m := newMetricsCollector()
+	m.add(errs, sub, failed)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(m))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, metric := range f.Metric {
+			labels := ""
+			for _, l := range metric.Label {
+				labels += l.GetName() + "=" + l.GetValue() + ","
+			}
+			switch {
+			case metric.Counter != nil:
+				values[f.GetName()+"{"+labels+"}"] = metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				values[f.GetName()+"{"+labels+"}"] = metric.Gauge.GetValue()
+			case metric.Histogram != nil:
+				values[f.GetName()+"_count{"+labels+"}"] = float64(metric.Histogram.GetSampleCount())
+				for _, b := range metric.Histogram.Bucket {
+					if b.GetUpperBound() == 0.016 {
+						values[f.GetName()+"_bucket{"+labels+"le=0.016}"] = float64(b.GetCumulativeCount())
+					}
+				}
+			}
+		}
+	}
+
+	video := "{kind=video,quality=low,room=room,}"
+	require.Equal(t, map[string]float64{
+		"livekit_loadtest_packets_total" + video:                                             100,
+		"livekit_loadtest_bytes_total" + video:                                               125000,
+		"livekit_loadtest_dropped_packets_total" + video:                                     2,
+		"livekit_loadtest_latency_seconds_count" + video:                                     3,
+		"livekit_loadtest_latency_seconds_bucket{kind=video,quality=low,room=room,le=0.016}": 1,
+		"livekit_loadtest_testers_connected{room=room,}":                                     0,
+		"livekit_loadtest_join_failures_total{room=room,}":                                   1,
+		"livekit_loadtest_reconnects_total{room=room,}":                                      1,
+	}, values)
+}
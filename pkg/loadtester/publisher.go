@@ -0,0 +1,342 @@
+package loadtester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+// PublishedResult is what a publisher sent on a track, or one simulcast layer of it, and the RTCP feedback
+// the SFU gave for it.
+// The server SDK drops the subscribed quality updates of dynacast, its publishers keep sending every layer,
+// so which layers the SFU paused is not known, see printPublishers.
+type PublishedResult struct {
+	TrackID string    `json:"track_id"`
+	Kind    TrackKind `json:"kind"`
+	// simulcast layer, empty for tracks without simulcast
+	Quality   string    `json:"quality,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// RTP packets sent
+	Packets int64 `json:"packets"`
+	// media bytes handed to the SDK, without RTP headers
+	Bytes int64 `json:"bytes"`
+	// packets the SFU asked to have resent, and its keyframe requests
+	NACKs int64 `json:"nacks,omitempty"`
+	PLIs  int64 `json:"plis,omitempty"`
+	FIRs  int64 `json:"firs,omitempty"`
+	// last receiver estimated maximum bitrate in bps, and transport-wide congestion control feedback packets
+	REMB int64 `json:"remb_bps,omitempty"`
+	TWCC int64 `json:"twcc_feedback,omitempty"`
+}
+
+// publishedStats counts what is sent of a track or simulcast layer while the publisher runs
+type publishedStats struct {
+	trackID   string
+	kind      TrackKind
+	quality   string
+	startedAt atomic.Time
+	endedAt   atomic.Time
+	packets   atomic.Int64
+	bytes     atomic.Int64
+	nacks     atomic.Int64
+	plis      atomic.Int64
+	firs      atomic.Int64
+	remb      atomic.Int64
+	twcc      atomic.Int64
+}
+
+// newPublishedStats is for a track of kind, layer is nil unless the track is a layer of a simulcast track
+func newPublishedStats(kind TrackKind, layer *livekit.VideoLayer) *publishedStats {
+	s := &publishedStats{kind: kind}
+	if layer != nil {
+		s.quality = strings.ToLower(layer.Quality.String())
+	}
+	return s
+}
+
+// onRTCP counts feedback for the track, it is called by the SDK for every RTCP packet of the track's sender
+func (s *publishedStats) onRTCP(pkt rtcp.Packet) {
+	switch p := pkt.(type) {
+	case *rtcp.TransportLayerNack:
+		for _, pair := range p.Nacks {
+			s.nacks.Add(int64(len(pair.PacketList())))
+		}
+	case *rtcp.PictureLossIndication:
+		s.plis.Inc()
+	case *rtcp.FullIntraRequest:
+		s.firs.Inc()
+	case *rtcp.ReceiverEstimatedMaximumBitrate:
+		s.remb.Store(int64(p.Bitrate))
+	case *rtcp.TransportLayerCC:
+		s.twcc.Inc()
+	}
+}
+
+func (s *publishedStats) result() *PublishedResult {
+	r := &PublishedResult{
+		TrackID:   s.trackID,
+		Kind:      s.kind,
+		Quality:   s.quality,
+		StartedAt: s.startedAt.Load(),
+		EndedAt:   s.endedAt.Load(),
+		Packets:   s.packets.Load(),
+		Bytes:     s.bytes.Load(),
+		NACKs:     s.nacks.Load(),
+		PLIs:      s.plis.Load(),
+		FIRs:      s.firs.Load(),
+		REMB:      s.remb.Load(),
+		TWCC:      s.twcc.Load(),
+	}
+	if r.EndedAt.IsZero() {
+		r.EndedAt = time.Now()
+	}
+	return r
+}
+
+// reset clears counters, the REMB estimate is kept as it is the latest one
+func (s *publishedStats) reset() {
+	if !s.startedAt.Load().IsZero() {
+		s.startedAt.Store(time.Now())
+	}
+	s.packets.Store(0)
+	s.bytes.Store(0)
+	s.nacks.Store(0)
+	s.plis.Store(0)
+	s.firs.Store(0)
+	s.twcc.Store(0)
+}
+
+// elapsed is the time the track has been sending for
+func (r *PublishedResult) elapsed() time.Duration {
+	if r.StartedAt.IsZero() {
+		return 0
+	}
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+// countingProvider counts the bytes of the samples it hands to the SDK, and the packets they are sent in
+type countingProvider struct {
+	lksdk.SampleProvider
+	stats *publishedStats
+	// the same as the SDK's LocalSampleTrack packetizes samples with, which gives no access to the packets it
+	// writes. Payloaders split samples the same way every time, so the count is that of the packets sent.
+	// Nil when the track counts packets itself.
+	payloader rtp.Payloader
+}
+
+// countingAudioProvider keeps audio levels of the provider it counts for
+type countingAudioProvider struct {
+	*countingProvider
+	audio lksdk.AudioSampleProvider
+}
+
+func (p *countingAudioProvider) CurrentAudioLevel() uint8 {
+	return p.audio.CurrentAudioLevel()
+}
+
+// countSent wraps provider so that the samples it sends are counted into stats, and their packets
+// when a payloader is given
+func countSent(provider lksdk.SampleProvider, stats *publishedStats, payloader rtp.Payloader) lksdk.SampleProvider {
+	p := &countingProvider{
+		SampleProvider: provider,
+		stats:          stats,
+		payloader:      payloader,
+	}
+	if audio, ok := provider.(lksdk.AudioSampleProvider); ok {
+		return &countingAudioProvider{countingProvider: p, audio: audio}
+	}
+	return p
+}
+
+func (p *countingProvider) NextSample() (media.Sample, error) {
+	sample, err := p.SampleProvider.NextSample()
+	if err != nil {
+		return sample, err
+	}
+
+	if p.stats.startedAt.Load().IsZero() {
+		p.stats.startedAt.Store(time.Now())
+	}
+	p.stats.bytes.Add(int64(len(sample.Data)))
+	if p.payloader != nil && len(sample.Data) > 0 {
+		p.stats.packets.Add(int64(len(p.payloader.Payload(rtpOutboundMTU-rtpHeaderSize, sample.Data))))
+	}
+	return sample, nil
+}
+
+// sdkPayloader returns a payloader like the one the SDK's LocalSampleTrack uses for codec
+func sdkPayloader(codec webrtc.RTPCodecCapability) (rtp.Payloader, error) {
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return &codecs.H264Payloader{}, nil
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return &codecs.OpusPayloader{}, nil
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return &codecs.VP8Payloader{EnablePictureID: true}, nil
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return &codecs.VP9Payloader{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported codec %s", codec.MimeType)
+	}
+}
+
+// newSampleTrack creates a track that sends samples of provider, counting what it sends and the feedback it gets into stats
+func newSampleTrack(provider lksdk.SampleProvider, codec webrtc.RTPCodecCapability, stats *publishedStats,
+	opts ...lksdk.LocalSampleTrackOptions,
+) (*lksdk.LocalSampleTrack, error) {
+	payloader, err := sdkPayloader(codec)
+	if err != nil {
+		return nil, err
+	}
+	track, err := lksdk.NewLocalSampleTrack(codec, append(opts, lksdk.WithRTCPHandler(stats.onRTCP))...)
+	if err != nil {
+		return nil, err
+	}
+	if err := track.StartWrite(countSent(provider, stats, payloader), nil); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// addPublished makes the stats of a published track part of the tester's stats
+func (t *LoadTester) addPublished(trackID string, stats ...*publishedStats) {
+	for _, s := range stats {
+		s.trackID = trackID
+		t.published.Store(trackID+"/"+s.quality, s)
+	}
+}
+
+func (t *LoadTester) publishedResults() []*PublishedResult {
+	var results []*PublishedResult
+	t.published.Range(func(_, value interface{}) bool {
+		results = append(results, value.(*publishedStats).result())
+		return true
+	})
+	sortPublished(results)
+	return results
+}
+
+var qualityOrder = map[string]int{"": 0, "high": 1, "medium": 2, "low": 3}
+
+func sortPublished(results []*PublishedResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].TrackID != results[j].TrackID {
+			return results[i].TrackID < results[j].TrackID
+		}
+		return qualityOrder[results[i].Quality] < qualityOrder[results[j].Quality]
+	})
+}
+
+// publishedSummary adds up a layer of the tracks published in a room
+type publishedSummary struct {
+	kind    TrackKind
+	quality string
+	tracks  int
+	packets int64
+	bytes   int64
+	elapsed time.Duration
+	nacks   int64
+	plis    int64
+	firs    int64
+	// REMB estimates over rembTracks tracks that received one
+	remb       int64
+	rembTracks int
+	twcc       int64
+}
+
+func summarizePublished(stats map[string]*testerStats) []*publishedSummary {
+	byLayer := make(map[string]*publishedSummary)
+	for _, ts := range stats {
+		for _, r := range ts.published {
+			key := string(r.Kind) + "/" + r.Quality
+			s := byLayer[key]
+			if s == nil {
+				s = &publishedSummary{kind: r.Kind, quality: r.Quality}
+				byLayer[key] = s
+			}
+
+			s.tracks++
+			s.packets += r.Packets
+			s.bytes += r.Bytes
+			s.elapsed += r.elapsed()
+			s.nacks += r.NACKs
+			s.plis += r.PLIs
+			s.firs += r.FIRs
+			if r.REMB > 0 {
+				s.remb += r.REMB
+				s.rembTracks++
+			}
+			s.twcc += r.TWCC
+		}
+	}
+
+	summaries := make([]*publishedSummary, 0, len(byLayer))
+	for _, s := range byLayer {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].kind != summaries[j].kind {
+			return summaries[i].kind > summaries[j].kind
+		}
+		return qualityOrder[summaries[i].quality] < qualityOrder[summaries[j].quality]
+	})
+	return summaries
+}
+
+// printPublishers shows per room and layer what publishers sent and the feedback the SFU gave them.
+// Nothing is printed when no publisher sent media.
+func (t *LoadTest) printPublishers(publishers map[string]map[string]*testerStats) {
+	rooms := make(map[string][]*publishedSummary)
+	names := make([]string, 0, len(publishers))
+	for room, stats := range publishers {
+		if summaries := summarizePublished(stats); len(summaries) > 0 {
+			rooms[room] = summaries
+			names = append(names, room)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	layered := false
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nPublisher\t| Room\t| Kind\t| Layer\t| Tracks\t| Packets\t| Bitrate avg\t| NACKs\t| PLIs\t| FIRs\t| REMB avg\t| TWCC\n")
+	for _, room := range names {
+		for _, s := range rooms[room] {
+			layer := s.quality
+			if layer == "" {
+				layer = " - "
+			} else {
+				layered = true
+			}
+			remb := " - "
+			if s.rembTracks > 0 {
+				remb = formatBitrate(s.remb/int64(s.rembTracks)/8, time.Second)
+			}
+			// of an average track
+			bitrate := formatBitrate(s.bytes/int64(s.tracks), s.elapsed/time.Duration(s.tracks))
+
+			_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %s\t| %d\t| %d\t| %s\t| %d\t| %d\t| %d\t| %s\t| %d\n",
+				room, s.kind, layer, s.tracks, s.packets, bitrate, s.nacks, s.plis, s.firs, remb, s.twcc)
+		}
+	}
+	_ = w.Flush()
+	if layered {
+		_, _ = fmt.Fprintln(t.Out, "Layers paused by dynacast are not reported: the server SDK does not surface the server's "+
+			"subscribed quality updates, so publishers send every layer throughout.")
+	}
+}
@@ -0,0 +1,147 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+type testSampleProvider struct {
+	lksdk.BaseSampleProvider
+	data []byte
+}
+
+func (p *testSampleProvider) NextSample() (media.Sample, error) {
+	return media.Sample{Data: p.data, Duration: 33 * time.Millisecond}, nil
+}
+
+type testAudioProvider struct {
+	testSampleProvider
+}
+
+func (p *testAudioProvider) CurrentAudioLevel() uint8 {
+	return 42
+}
+
+func TestPublishedRTCP(t *testing.T) {
+	s := newPublishedStats(TrackKindVideo, &livekit.VideoLayer{Quality: livekit.VideoQuality_MEDIUM})
+	require.Equal(t, "medium", s.quality)
+
+	// packet 10, and 11 and 13 through the bitmask
+	s.onRTCP(&rtcp.TransportLayerNack{Nacks: []rtcp.NackPair{{PacketID: 10, LostPackets: 0b101}}})
+	s.onRTCP(&rtcp.PictureLossIndication{})
+	s.onRTCP(&rtcp.PictureLossIndication{})
+	s.onRTCP(&rtcp.FullIntraRequest{})
+	s.onRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_000_000})
+	s.onRTCP(&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 800_000})
+	s.onRTCP(&rtcp.TransportLayerCC{})
+	s.onRTCP(&rtcp.ReceiverReport{})
+
+	r := s.result()
+	require.Equal(t, int64(3), r.NACKs)
+	require.Equal(t, int64(2), r.PLIs)
+	require.Equal(t, int64(1), r.FIRs)
+	require.Equal(t, int64(800_000), r.REMB)
+	require.Equal(t, int64(1), r.TWCC)
+
+	s.reset()
+	r = s.result()
+	require.Zero(t, r.NACKs)
+	require.Zero(t, r.PLIs)
+	require.Equal(t, int64(800_000), r.REMB)
+}
+
+func TestCountSent(t *testing.T) {
+	s := newPublishedStats(TrackKindVideo, nil)
+	p := countSent(&testSampleProvider{data: make([]byte, 1500)}, s, nil)
+	_, isAudio := p.(lksdk.AudioSampleProvider)
+	require.False(t, isAudio)
+
+	_, err := p.NextSample()
+	require.NoError(t, err)
+	r := s.result()
+	require.Equal(t, int64(1500), r.Bytes)
+	require.False(t, r.StartedAt.IsZero())
+
+	// audio levels are passed on
+	s = newPublishedStats(TrackKindAudio, nil)
+	p = countSent(&testAudioProvider{testSampleProvider{data: make([]byte, 100)}}, s, nil)
+	audio, isAudio := p.(lksdk.AudioSampleProvider)
+	require.True(t, isAudio)
+	require.Equal(t, uint8(42), audio.CurrentAudioLevel())
+	_, err = p.NextSample()
+	require.NoError(t, err)
+	require.Equal(t, int64(100), s.bytes.Load())
+}
+
+func TestPublishedPackets(t *testing.T) {
+	s := newPublishedStats(TrackKindVideo, nil)
+	payloader, err := sdkPayloader(webrtc.RTPCodecCapability{MimeType: "video/h264"})
+	require.NoError(t, err)
+
+	// an Annex B slice of 3000 bytes, sent as three FU-A packets
+	slice := append([]byte{0, 0, 0, 1, 0x65}, make([]byte, 2999)...)
+	p := countSent(&testSampleProvider{data: slice}, s, payloader)
+	_, err = p.NextSample()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), s.result().Packets)
+
+	s.reset()
+	require.Zero(t, s.result().Packets)
+	_, err = p.NextSample()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), s.result().Packets)
+
+	_, err = sdkPayloader(webrtc.RTPCodecCapability{MimeType: "video/av1"})
+	require.Error(t, err)
+}
+
+func TestSummarizePublished(t *testing.T) {
+	start := time.Unix(0, 0)
+	published := func(trackID, quality string, remb int64) *PublishedResult {
+		return &PublishedResult{
+			TrackID:   trackID,
+			Kind:      TrackKindVideo,
+			Quality:   quality,
+			StartedAt: start,
+			EndedAt:   start.Add(10 * time.Second),
+			Packets:   100,
+			Bytes:     100_000,
+			PLIs:      1,
+			REMB:      remb,
+		}
+	}
+	publishers := map[string]*testerStats{
+		"Pub 0": {published: []*PublishedResult{published("TR_a", "high", 0), published("TR_a", "low", 500_000)}},
+		"Pub 1": {published: []*PublishedResult{
+			published("TR_b", "high", 0), published("TR_b", "low", 300_000),
+			{TrackID: "TR_c", Kind: TrackKindAudio, Packets: 50},
+		}},
+	}
+	summaries := summarizePublished(publishers)
+	require.Len(t, summaries, 3)
+
+	high := summaries[0]
+	require.Equal(t, TrackKindVideo, high.kind)
+	require.Equal(t, "high", high.quality)
+	require.Equal(t, 2, high.tracks)
+	require.Equal(t, int64(200), high.packets)
+	require.Equal(t, int64(2), high.plis)
+	require.Zero(t, high.rembTracks)
+
+	low := summaries[1]
+	require.Equal(t, "low", low.quality)
+	require.Equal(t, int64(800_000), low.remb)
+	require.Equal(t, 2, low.rembTracks)
+
+	audio := summaries[2]
+	require.Equal(t, TrackKindAudio, audio.kind)
+	require.Equal(t, int64(50), audio.packets)
+}
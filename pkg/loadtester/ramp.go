@@ -0,0 +1,374 @@
+package loadtester
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/sync/syncmap"
+)
+
+const rampInterval = 200 * time.Millisecond
+
+// Phase is one stage of a load profile. Over Duration the number of subscribers in every room
+// moves linearly from the previous phase's target (zero for the first phase) to Subscribers.
+// A phase with the same target as the previous one holds the load steady.
+type Phase struct {
+	Name        string        `yaml:"name"`
+	Duration    time.Duration `yaml:"duration"`
+	Subscribers int           `yaml:"subscribers"`
+}
+
+// ParsePhase parses a phase in the form name:duration:subscribers, e.g. ramp-up:20m:1000
+func ParsePhase(s string) (Phase, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return Phase{}, fmt.Errorf("invalid phase %q, expected name:duration:subscribers", s)
+	}
+
+	duration, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return Phase{}, fmt.Errorf("invalid phase %q: %w", s, err)
+	}
+
+	subscribers, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Phase{}, fmt.Errorf("invalid phase %q: %w", s, err)
+	}
+
+	p := Phase{
+		Name:        parts[0],
+		Duration:    duration,
+		Subscribers: subscribers,
+	}
+
+	return p, p.Validate()
+}
+
+func (p Phase) Validate() error {
+	if p.Duration <= 0 {
+		return fmt.Errorf("phase %s must have a positive duration", p.Name)
+	}
+
+	if p.Subscribers < 0 {
+		return fmt.Errorf("phase %s cannot have negative subscribers", p.Name)
+	}
+
+	return nil
+}
+
+func maxPhaseSubscribers(phases []Phase) int {
+	m := 0
+	for _, p := range phases {
+		if p.Subscribers > m {
+			m = p.Subscribers
+		}
+	}
+
+	return m
+}
+
+type phaseStats struct {
+	name     string
+	duration time.Duration
+	// subscribers per room at the end of the phase
+	subscribers int
+	stats       map[string]map[string]*testerStats
+}
+
+type rampSubscriber struct {
+	tester  *LoadTester
+	started chan struct{}
+}
+
+// rampRoom tracks the subscribers currently connected to one room during a ramp
+type rampRoom struct {
+	subParam *trackParams
+	active   []*rampSubscriber
+	created  int
+}
+
+type rampRunner struct {
+	params Params
+	rooms  []*rampRoom
+	// every subscriber ever started, including the ones already removed
+	testers []*LoadTester
+	errs    syncmap.Map
+	ready   chan struct{}
+	// refreshed from the ramp loop, nil unless enabled
+	dash       *dashboard
+	publishers []*LoadTester
+	metrics    *metricsCollector
+	// subscribers resize is stopping, which may still be joining
+	stopping sync.WaitGroup
+}
+
+// runPhases drives subscribers through the configured phases, reporting stats for every phase
+func (t *LoadTest) runPhases(ctx context.Context, params Params, subParams []*trackParams, publishers []*LoadTester) (*testResult, error) {
+	r := &rampRunner{
+		params:     params,
+		ready:      make(chan struct{}),
+		publishers: publishers,
+		metrics:    t.metrics,
+	}
+	if params.Dashboard {
+		r.dash = newDashboard(t.Out, &r.errs)
+	}
+	// subscribers joining mid-test start publishing data right away
+	close(r.ready)
+
+	for _, subParam := range subParams {
+		r.rooms = append(r.rooms, &rampRoom{subParam: subParam})
+	}
+
+	var speakerSim *SpeakerSimulator
+	if len(publishers) > 0 && params.SimulateSpeakers {
+		speakerSim = NewSpeakerSimulator(SpeakerSimulatorParams{
+			Testers: publishers,
+		})
+		speakerSim.Start()
+	}
+
+	result := &testResult{}
+	current := 0
+	for i, phase := range params.Phases {
+		name := phase.Name
+		if name == "" {
+			name = fmt.Sprintf("phase %d", i+1)
+		}
+
+		if r.dash != nil {
+			r.dash.keep()
+		}
+		fmt.Fprintf(t.Out, "\rStarting phase %s: %d -> %d subscribers per room over %s                   \n",
+			name, current, phase.Subscribers, phase.Duration)
+
+		before, firstNew := r.snapshot(), len(r.testers)
+		err := r.ramp(ctx, current, phase)
+		ps := &phaseStats{
+			name:        name,
+			duration:    phase.Duration,
+			subscribers: r.count(),
+			stats:       r.phaseDelta(before, firstNew),
+		}
+		result.phases = append(result.phases, ps)
+		current = phase.Subscribers
+
+		if err != nil {
+			break
+		}
+	}
+
+	if speakerSim != nil {
+		speakerSim.Stop()
+	}
+
+	for _, room := range r.rooms {
+		r.resize(room, 0)
+	}
+	// the stats of removed subscribers are complete once they stopped
+	r.stopping.Wait()
+	for _, tester := range r.testers {
+		tester.Stop()
+	}
+	for _, p := range publishers {
+		p.Stop()
+	}
+
+	result.stats = collectStats(r.testers, &r.errs)
+	result.rooms = roomNames(subParams)
+
+	return result, nil
+}
+
+// ramp moves every room linearly from the from count to the phase target, returning early when canceled
+func (r *rampRunner) ramp(ctx context.Context, from int, phase Phase) error {
+	ticker := time.NewTicker(rampInterval)
+	defer ticker.Stop()
+
+	startedAt := time.Now()
+	for {
+		progress := float64(time.Since(startedAt)) / float64(phase.Duration)
+		if progress > 1 {
+			progress = 1
+		}
+
+		want := int(math.Round(float64(from) + float64(phase.Subscribers-from)*progress))
+		for _, room := range r.rooms {
+			r.resize(room, want)
+		}
+		if r.dash != nil {
+			r.dash.refresh(time.Now(), r.active())
+		}
+
+		if progress == 1 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// resize starts or stops subscribers so the room has exactly want of them.
+// The most recently added subscribers leave first, keeping the quality split stable.
+func (r *rampRunner) resize(room *rampRoom, want int) {
+	for len(room.active) < want {
+		slot := len(room.active)
+		tester := newSubscriber(r.params, room.subParam, room.created, viewerQuality(r.params, slot), &r.errs)
+		room.created++
+		if tester == nil {
+			// publisher is missing, the error is already recorded
+			room.active = append(room.active, &rampSubscriber{started: closedChan()})
+			continue
+		}
+
+		sub := &rampSubscriber{
+			tester:  tester,
+			started: make(chan struct{}),
+		}
+		room.active = append(room.active, sub)
+		r.testers = append(r.testers, tester)
+		r.metrics.add(&r.errs, tester)
+
+		publishData := slot < r.params.DataPublishers
+		go func() {
+			defer close(sub.started)
+			startSubscriber(r.params, sub.tester, publishData, r.ready, &r.errs)
+		}()
+	}
+
+	for len(room.active) > want {
+		sub := room.active[len(room.active)-1]
+		room.active = room.active[:len(room.active)-1]
+		if sub.tester == nil {
+			continue
+		}
+
+		r.stopping.Add(1)
+		go func() {
+			defer r.stopping.Done()
+			// joining may still be in progress
+			<-sub.started
+			sub.tester.Stop()
+		}()
+	}
+}
+
+// active returns the publishers and the subscribers that are currently in a room
+func (r *rampRunner) active() []*LoadTester {
+	active := append([]*LoadTester{}, r.publishers...)
+	for _, room := range r.rooms {
+		for _, sub := range room.active {
+			if sub.tester != nil {
+				active = append(active, sub.tester)
+			}
+		}
+	}
+
+	return active
+}
+
+func (r *rampRunner) count() int {
+	if len(r.rooms) == 0 {
+		return 0
+	}
+
+	return len(r.rooms[0].active)
+}
+
+// snapshot takes the stats of subscribers that are currently in a room
+func (r *rampRunner) snapshot() map[*LoadTester]*testerStats {
+	stats := make(map[*LoadTester]*testerStats)
+	for _, room := range r.rooms {
+		for _, sub := range room.active {
+			if sub.tester != nil {
+				stats[sub.tester] = sub.tester.getStats()
+			}
+		}
+	}
+
+	return stats
+}
+
+// phaseDelta returns stats accumulated since the before snapshot, for subscribers that were in a room
+// when the phase started and for the ones added during the phase (from index firstNew on)
+func (r *rampRunner) phaseDelta(before map[*LoadTester]*testerStats, firstNew int) map[string]map[string]*testerStats {
+	stats := make(map[string]map[string]*testerStats)
+	for i, tester := range r.testers {
+		prev, existed := before[tester]
+		if !existed && i < firstNew {
+			// left before the phase started
+			continue
+		}
+
+		cur := tester.getStats()
+		if prev != nil {
+			for id, s := range cur.stats {
+				cur.stats[id] = s.delta(prev.stats[id])
+			}
+		}
+
+		if stats[tester.params.Room] == nil {
+			stats[tester.params.Room] = make(map[string]*testerStats)
+		}
+		if e, _ := r.errs.Load(tester.params.name); e != nil {
+			cur.err = e.(error)
+		}
+		stats[tester.params.Room][tester.params.name] = cur
+	}
+
+	return stats
+}
+
+func (t *LoadTest) printPhases(phases []*phaseStats) {
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nPhases\n")
+	_, _ = fmt.Fprint(w, "\nPhase\t| Duration\t| Subscribers\t| Room\t| Kind\t| Tracks\t| Bitrate\t| Latency\t| Total Dropped\t| Errors\n")
+
+	for _, phase := range phases {
+		rooms := make([]string, 0, len(phase.stats))
+		for room := range phase.stats {
+			rooms = append(rooms, room)
+		}
+		sort.Strings(rooms)
+
+		for _, room := range rooms {
+			summaries := make(map[string][]*summary)
+			for name, stats := range phase.stats[room] {
+				if len(stats.stats) == 0 && stats.err == nil {
+					continue
+				}
+				summaries[name] = getTesterSummary(stats, t.Params.DataPublishers > 0, t.Params.WithAudio)
+			}
+
+			for _, s := range getTestSummary(summaries, t.Params.DataPublishers > 0, t.Params.WithAudio) {
+				if s.tracks == 0 {
+					continue
+				}
+
+				sLatency, sDropped := formatStrings(s.packets, s.latency, s.latencyCount, s.dropped)
+				_, _ = fmt.Fprintf(w, "%s\t| %s\t| %d\t| %s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %d\n",
+					phase.name, phase.duration, phase.subscribers, room, s.kind, s.tracks,
+					formatBitrate(s.bytes, s.elapsed), sLatency, sDropped, s.errCount)
+			}
+		}
+	}
+
+	_ = w.Flush()
+}
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
@@ -0,0 +1,204 @@
+package loadtester
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// newRampRunner returns a runner whose rooms lost their publisher, so subscribers are placeholders
+// that don't connect anywhere
+func newRampRunner(rooms ...string) *rampRunner {
+	r := &rampRunner{ready: closedChan()}
+	for _, room := range rooms {
+		r.rooms = append(r.rooms, &rampRoom{subParam: &trackParams{roomName: room, err: errors.New("no publisher")}})
+	}
+	return r
+}
+
+// newRampTester returns a subscriber of room with a video track that received packets
+func newRampTester(room, name string, packets int64) *LoadTester {
+	tester := NewLoadTester(TesterParams{Room: room, name: name}, livekit.VideoQuality_HIGH)
+	s := &trackStats{trackID: "TR_video", kind: TrackKindVideo}
+	s.packets.Store(packets)
+	tester.stats.Store(s.trackID, s)
+	return tester
+}
+
+func TestRampResize(t *testing.T) {
+	cases := map[string]struct {
+		sizes       []int
+		wantActive  int
+		wantCreated int
+	}{
+		"grow":             {sizes: []int{3}, wantActive: 3, wantCreated: 3},
+		"shrink":           {sizes: []int{4, 1}, wantActive: 1, wantCreated: 4},
+		"regrow":           {sizes: []int{4, 1, 3}, wantActive: 3, wantCreated: 6},
+		"empty":            {sizes: []int{2, 0}, wantActive: 0, wantCreated: 2},
+		"same size":        {sizes: []int{2, 2}, wantActive: 2, wantCreated: 2},
+		"never subscribed": {sizes: []int{0}, wantActive: 0, wantCreated: 0},
+	}
+
+	for name, c := range cases {
+		r := newRampRunner("room_0", "room_1")
+		for _, size := range c.sizes {
+			for _, room := range r.rooms {
+				r.resize(room, size)
+			}
+		}
+
+		require.Equal(t, c.wantActive, r.count(), name)
+		for _, room := range r.rooms {
+			require.Len(t, room.active, c.wantActive, name)
+			// subscribers get a new sequence number every time they join
+			require.Equal(t, c.wantCreated, room.created, name)
+		}
+		require.Empty(t, r.testers, name)
+		require.Empty(t, r.active(), name)
+		_, failed := r.errs.Load("Sub 0 in room_1")
+		require.Equal(t, c.wantCreated > 0, failed, name)
+	}
+}
+
+func TestRampResizeStopping(t *testing.T) {
+	r := newRampRunner("room_0")
+	started := make(chan struct{})
+	r.rooms[0].active = []*rampSubscriber{{tester: newRampTester("room_0", "Sub 0", 10), started: started}}
+
+	// the subscriber is still joining, stopping it waits for that
+	r.resize(r.rooms[0], 0)
+	require.Empty(t, r.rooms[0].active)
+	stopped := make(chan struct{})
+	go func() {
+		r.stopping.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stopped before joining finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(started)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("not stopped")
+	}
+}
+
+func TestRampPhase(t *testing.T) {
+	cases := map[string]struct {
+		from     int
+		phase    Phase
+		cancel   bool
+		wantSize int
+		wantErr  bool
+	}{
+		"ramp up":       {from: 0, phase: Phase{Duration: time.Millisecond, Subscribers: 5}, wantSize: 5},
+		"ramp down":     {from: 5, phase: Phase{Duration: time.Millisecond, Subscribers: 2}, wantSize: 2},
+		"hold":          {from: 3, phase: Phase{Duration: time.Millisecond, Subscribers: 3}, wantSize: 3},
+		"canceled":      {from: 2, phase: Phase{Duration: time.Hour, Subscribers: 10}, cancel: true, wantSize: 2, wantErr: true},
+		"canceled down": {from: 4, phase: Phase{Duration: time.Hour, Subscribers: 0}, cancel: true, wantSize: 4, wantErr: true},
+	}
+
+	for name, c := range cases {
+		r := newRampRunner("room_0")
+		r.resize(r.rooms[0], c.from)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if c.cancel {
+			cancel()
+		}
+		err := r.ramp(ctx, c.from, c.phase)
+		cancel()
+
+		require.Equal(t, c.wantErr, err != nil, name)
+		require.Equal(t, c.wantSize, r.count(), name)
+	}
+}
+
+func TestPhaseDelta(t *testing.T) {
+	// left during an earlier phase
+	gone := newRampTester("room_0", "Sub 0 in room_0", 40)
+	// in the room when the phase starts, leaving or staying during it
+	staying := newRampTester("room_0", "Sub 1 in room_0", 100)
+	leaving := newRampTester("room_0", "Sub 2 in room_0", 50)
+	// added during the phase
+	added := newRampTester("room_1", "Sub 0 in room_1", 30)
+
+	cases := map[string]struct {
+		testers  []*LoadTester
+		before   []*LoadTester
+		firstNew int
+		errs     map[string]error
+		// packets per tester name
+		want map[string]int64
+	}{
+		"existing": {
+			testers:  []*LoadTester{staying},
+			before:   []*LoadTester{staying},
+			firstNew: 1,
+			want:     map[string]int64{"Sub 1 in room_0": 40},
+		},
+		"left before the phase": {
+			testers:  []*LoadTester{gone, staying},
+			before:   []*LoadTester{staying},
+			firstNew: 2,
+			want:     map[string]int64{"Sub 1 in room_0": 40},
+		},
+		"left during the phase": {
+			testers:  []*LoadTester{staying, leaving},
+			before:   []*LoadTester{staying, leaving},
+			firstNew: 2,
+			want:     map[string]int64{"Sub 1 in room_0": 40, "Sub 2 in room_0": 40},
+		},
+		"added during the phase": {
+			testers:  []*LoadTester{gone, staying, added},
+			before:   []*LoadTester{staying},
+			firstNew: 2,
+			want:     map[string]int64{"Sub 1 in room_0": 40, "Sub 0 in room_1": 30},
+		},
+		"first phase": {
+			testers:  []*LoadTester{staying, added},
+			firstNew: 0,
+			want:     map[string]int64{"Sub 1 in room_0": 100, "Sub 0 in room_1": 30},
+		},
+		"errors": {
+			testers:  []*LoadTester{staying, added},
+			before:   []*LoadTester{staying},
+			firstNew: 1,
+			errs:     map[string]error{"Sub 0 in room_1": errors.New("could not connect")},
+			want:     map[string]int64{"Sub 1 in room_0": 40, "Sub 0 in room_1": 30},
+		},
+	}
+
+	for name, c := range cases {
+		r := &rampRunner{testers: c.testers}
+		before := make(map[*LoadTester]*testerStats)
+		for _, tester := range c.before {
+			stats := tester.getStats()
+			// 40 packets arrive after the snapshot
+			stats.stats["TR_video"].packets.Sub(40)
+			before[tester] = stats
+		}
+		for tester, err := range c.errs {
+			r.errs.Store(tester, err)
+		}
+
+		got := make(map[string]int64)
+		for room, testers := range r.phaseDelta(before, c.firstNew) {
+			for testerName, stats := range testers {
+				require.Equal(t, room, testerName[len(testerName)-len(room):], name)
+				got[testerName] = stats.stats["TR_video"].packets.Load()
+				require.Equal(t, c.errs[testerName], stats.err, name)
+			}
+		}
+		require.Equal(t, c.want, got, name)
+	}
+}
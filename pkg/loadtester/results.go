@@ -0,0 +1,210 @@
+package loadtester
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// TesterResult is the serializable form of a tester's stats
+type TesterResult struct {
+	Room   string         `json:"room"`
+	Name   string         `json:"name"`
+	Error  string         `json:"error,omitempty"`
+	Tracks []*TrackResult `json:"tracks"`
+	// zero when the tester did not join
+	JoinDuration time.Duration `json:"join_duration,omitempty"`
+	JoinAttempts int           `json:"join_attempts,omitempty"`
+	JoinMilestones
+	// tracks and simulcast layers of publishers
+	Published []*PublishedResult `json:"published,omitempty"`
+}
+
+type TrackResult struct {
+	TrackID      string    `json:"track_id"`
+	Kind         TrackKind `json:"kind"`
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	Packets      int64     `json:"packets"`
+	Bytes        int64     `json:"bytes"`
+	Dropped      int64     `json:"dropped"`
+	LatencyTotal int64     `json:"latency_total_ns"`
+	LatencyCount int64     `json:"latency_count"`
+	// latency histogram, bucket index => count
+	LatencyBuckets map[string]int64 `json:"latency_buckets,omitempty"`
+	LatencyMax     int64            `json:"latency_max_ns,omitempty"`
+	// send times too far off the reference clock to be used
+	LatencyDiscarded int64 `json:"latency_discarded,omitempty"`
+	// RTP sequence number accounting, not set for data
+	RTPExpected   int64 `json:"rtp_expected,omitempty"`
+	RTPReceived   int64 `json:"rtp_received,omitempty"`
+	RTPDuplicates int64 `json:"rtp_duplicates,omitempty"`
+	RTPOutOfOrder int64 `json:"rtp_out_of_order,omitempty"`
+	Jitter        int64 `json:"jitter_ns,omitempty"`
+	// bitrate the track was published at in bps, zero when unknown
+	ExpectedBitrate int64 `json:"expected_bitrate,omitempty"`
+	// longest time without packets, and counters per interval when Params.Interval is set
+	LongestStall time.Duration     `json:"longest_stall_ns,omitempty"`
+	Intervals    []*IntervalResult `json:"intervals,omitempty"`
+	// video quality the subscriber asked for, and packets per SVC layer when packets carry layer indices
+	Requested string         `json:"requested,omitempty"`
+	Layers    []*LayerResult `json:"layers,omitempty"`
+	// decodable video frames, freezes between them and the time from subscribing to the first keyframe
+	Frames        int64         `json:"frames,omitempty"`
+	Freezes       int64         `json:"freezes,omitempty"`
+	FreezeTime    time.Duration `json:"freeze_time_ns,omitempty"`
+	FirstKeyframe time.Duration `json:"first_keyframe_ns,omitempty"`
+	// time on each simulcast layer received, layer switches, and the time from subscribing to the requested layer
+	Delivered       map[string]time.Duration `json:"delivered_ns,omitempty"`
+	LayerSwitches   int64                    `json:"layer_switches,omitempty"`
+	TimeToRequested time.Duration            `json:"time_to_requested_ns,omitempty"`
+}
+
+// PhaseResult is the serializable form of a phase's stats
+type PhaseResult struct {
+	Name        string          `json:"name"`
+	Duration    time.Duration   `json:"duration"`
+	Subscribers int             `json:"subscribers"`
+	Testers     []*TesterResult `json:"testers"`
+}
+
+func testerResults(stats map[string]map[string]*testerStats) []*TesterResult {
+	var results []*TesterResult
+	for room, roomStats := range stats {
+		for name, ts := range roomStats {
+			r := &TesterResult{
+				Room:           room,
+				Name:           name,
+				JoinDuration:   ts.joinDuration,
+				JoinAttempts:   ts.joinAttempts,
+				JoinMilestones: ts.milestones,
+				Published:      ts.published,
+			}
+			if ts.err != nil {
+				r.Error = ts.err.Error()
+			}
+
+			for _, s := range ts.stats {
+				delivered, switches := s.deliveredTimes(s.endedAt.Load())
+				r.Tracks = append(r.Tracks, &TrackResult{
+					TrackID:          s.trackID,
+					Kind:             s.kind,
+					StartedAt:        s.startedAt.Load(),
+					EndedAt:          s.endedAt.Load(),
+					Packets:          s.packets.Load(),
+					Bytes:            s.bytes.Load(),
+					Dropped:          s.dropped.Load(),
+					LatencyTotal:     s.latency.Load(),
+					LatencyCount:     s.latencyCount.Load(),
+					LatencyBuckets:   s.latencyHist.nonEmpty(),
+					LatencyMax:       s.latencyHist.max.Load(),
+					LatencyDiscarded: s.latencyDiscarded.Load(),
+					RTPExpected:      s.rtpExpected.Load(),
+					RTPReceived:      s.rtpReceived.Load(),
+					RTPDuplicates:    s.rtpDuplicates.Load(),
+					RTPOutOfOrder:    s.rtpOutOfOrder.Load(),
+					Jitter:           s.jitter.Load(),
+					ExpectedBitrate:  s.expectedBitrate.Load(),
+					LongestStall:     s.longestStall.Load(),
+					Intervals:        s.intervalResults(),
+					Requested:        s.requested,
+					Layers:           s.layerResults(),
+					Frames:           s.frames.Load(),
+					Freezes:          s.freezes.Load(),
+					FreezeTime:       s.freezeTime.Load(),
+					FirstKeyframe:    s.firstKeyframe.Load(),
+					Delivered:        delivered,
+					LayerSwitches:    switches,
+					TimeToRequested:  s.timeToRequested.Load(),
+				})
+			}
+			sort.Slice(r.Tracks, func(i, j int) bool {
+				return r.Tracks[i].TrackID < r.Tracks[j].TrackID
+			})
+
+			results = append(results, r)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Room != results[j].Room {
+			return results[i].Room < results[j].Room
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
+
+func phaseResults(phases []*phaseStats) []*PhaseResult {
+	results := make([]*PhaseResult, 0, len(phases))
+	for _, p := range phases {
+		results = append(results, &PhaseResult{
+			Name:        p.name,
+			Duration:    p.duration,
+			Subscribers: p.subscribers,
+			Testers:     testerResults(p.stats),
+		})
+	}
+
+	return results
+}
+
+// addTesterResults adds results back into stats, as used for reporting.
+// label is appended to tester names that are already present, e.g. when merging results of several agents.
+func addTesterResults(stats map[string]map[string]*testerStats, results []*TesterResult, label string) {
+	for _, r := range results {
+		ts := &testerStats{
+			stats:        make(map[string]*trackStats),
+			joinDuration: r.JoinDuration,
+			joinAttempts: r.JoinAttempts,
+			milestones:   r.JoinMilestones,
+			published:    r.Published,
+		}
+		if r.Error != "" {
+			ts.err = errors.New(r.Error)
+		}
+
+		for _, t := range r.Tracks {
+			s := &trackStats{
+				trackID:   t.TrackID,
+				kind:      t.Kind,
+				requested: t.Requested,
+			}
+			s.startedAt.Store(t.StartedAt)
+			s.endedAt.Store(t.EndedAt)
+			s.packets.Store(t.Packets)
+			s.bytes.Store(t.Bytes)
+			s.dropped.Store(t.Dropped)
+			s.latency.Store(t.LatencyTotal)
+			s.latencyCount.Store(t.LatencyCount)
+			s.latencyHist.load(t.LatencyBuckets, t.LatencyMax)
+			s.latencyDiscarded.Store(t.LatencyDiscarded)
+			s.rtpExpected.Store(t.RTPExpected)
+			s.rtpReceived.Store(t.RTPReceived)
+			s.rtpDuplicates.Store(t.RTPDuplicates)
+			s.rtpOutOfOrder.Store(t.RTPOutOfOrder)
+			s.jitter.Store(t.Jitter)
+			s.expectedBitrate.Store(t.ExpectedBitrate)
+			s.longestStall.Store(t.LongestStall)
+			s.setIntervals(t.Intervals)
+			s.setLayers(t.Layers)
+			s.frames.Store(t.Frames)
+			s.freezes.Store(t.Freezes)
+			s.freezeTime.Store(t.FreezeTime)
+			s.firstKeyframe.Store(t.FirstKeyframe)
+			s.setDelivered(t.Delivered, t.LayerSwitches)
+			s.timeToRequested.Store(t.TimeToRequested)
+			ts.stats[t.TrackID] = s
+		}
+
+		if stats[r.Room] == nil {
+			stats[r.Room] = make(map[string]*testerStats)
+		}
+		name := r.Name
+		if _, ok := stats[r.Room][name]; ok && label != "" {
+			name = name + " (" + label + ")"
+		}
+		stats[r.Room][name] = ts
+	}
+}
@@ -0,0 +1,107 @@
+package loadtester
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// packets remembered to tell duplicates from late packets
+const sequenceWindow = 1024
+
+// sequenceTracker follows RTP sequence numbers and timestamps of one track as described in RFC 3550,
+// appendix A.1 and A.8. It is only used from the goroutine reading the track and reports into trackStats.
+type sequenceTracker struct {
+	clockRate float64
+	started   bool
+	// extended (with wrap-arounds) first and highest sequence number
+	base   int64
+	maxSeq int64
+	seen   [sequenceWindow]int64
+
+	// jitter state, in RTP timestamp units
+	firstArrival time.Time
+	hasTransit   bool
+	lastTransit  float64
+	jitter       float64
+}
+
+func newSequenceTracker(clockRate uint32) *sequenceTracker {
+	return &sequenceTracker{clockRate: float64(clockRate)}
+}
+
+func (s *sequenceTracker) push(pkt *rtp.Packet, arrival time.Time, stats *trackStats) {
+	if !s.started {
+		s.started = true
+		s.base = int64(pkt.SequenceNumber)
+		s.maxSeq = s.base
+		s.firstArrival = arrival
+		s.markSeen(s.base)
+		stats.rtpExpected.Inc()
+		stats.rtpReceived.Inc()
+		s.updateJitter(pkt, arrival, stats)
+		return
+	}
+
+	// closest extended sequence number to the highest one seen so far
+	ext := s.maxSeq + int64(int16(pkt.SequenceNumber-uint16(s.maxSeq)))
+
+	switch {
+	case ext > s.maxSeq:
+		stats.rtpExpected.Add(ext - s.maxSeq)
+		s.maxSeq = ext
+
+	case s.wasSeen(ext):
+		stats.rtpDuplicates.Inc()
+		return
+
+	default:
+		stats.rtpOutOfOrder.Inc()
+		if ext < s.base {
+			// older than the first packet, it was not expected yet
+			s.base = ext
+			stats.rtpExpected.Inc()
+		}
+	}
+
+	s.markSeen(ext)
+	stats.rtpReceived.Inc()
+	s.updateJitter(pkt, arrival, stats)
+}
+
+func (s *sequenceTracker) markSeen(ext int64) {
+	s.seen[windowIndex(ext)] = ext + 1
+}
+
+func (s *sequenceTracker) wasSeen(ext int64) bool {
+	if s.maxSeq-ext >= sequenceWindow {
+		// too old to tell, count it as a duplicate rather than inflating received packets
+		return true
+	}
+	return s.seen[windowIndex(ext)] == ext+1
+}
+
+func windowIndex(ext int64) int64 {
+	return (ext%sequenceWindow + sequenceWindow) % sequenceWindow
+}
+
+// updateJitter implements the interarrival jitter estimate of RFC 3550 section 6.4.1
+func (s *sequenceTracker) updateJitter(pkt *rtp.Packet, arrival time.Time, stats *trackStats) {
+	if s.clockRate == 0 {
+		return
+	}
+
+	transit := arrival.Sub(s.firstArrival).Seconds()*s.clockRate - float64(pkt.Timestamp)
+	if s.hasTransit {
+		d := math.Abs(transit - s.lastTransit)
+		// skip jumps from timestamps wrapping around or the stream restarting
+		if d < s.clockRate*10 {
+			s.jitter += (d - s.jitter) / 16
+		}
+	}
+	s.lastTransit = transit
+	s.hasTransit = true
+
+	stats.jitter.Store(int64(s.jitter / s.clockRate * float64(time.Second)))
+}
@@ -0,0 +1,33 @@
+package loadtester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	stats := &trackStats{}
+	s := newSequenceTracker(90000)
+	start := time.Now()
+
+	// wraps around, 65534 is lost, 1 arrives late and 2 twice
+	for i, seq := range []uint16{65532, 65533, 65535, 0, 2, 1, 2, 3} {
+		s.push(&rtp.Packet{Header: rtp.Header{
+			SequenceNumber: seq,
+			Timestamp:      uint32(i * 3000),
+		}}, start.Add(time.Duration(i)*33*time.Millisecond), stats)
+	}
+
+	require.Equal(t, int64(8), stats.rtpExpected.Load())
+	require.Equal(t, int64(7), stats.rtpReceived.Load())
+	require.Equal(t, int64(1), stats.rtpLost())
+	require.Equal(t, int64(1), stats.rtpDuplicates.Load())
+	require.Equal(t, int64(1), stats.rtpOutOfOrder.Load())
+
+	// 33ms between packets against 3000 / 90000 = 33.3ms of media, so jitter stays well below a millisecond
+	require.Less(t, time.Duration(stats.jitter.Load()), time.Millisecond)
+	require.Greater(t, stats.jitter.Load(), int64(0))
+}
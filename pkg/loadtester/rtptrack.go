@@ -0,0 +1,139 @@
+package loadtester
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+const (
+	// same as the SDK's LocalSampleTrack
+	rtpOutboundMTU = 1200
+	// without extensions, packetizers leave this much of the MTU out of payloads
+	rtpHeaderSize = 12
+)
+
+// rtpTrack sends the samples of a provider packetized by a payloader of its own, for streams the payloaders of
+// the SDK's LocalSampleTrack cannot packetize, like VP9 with SVC layers. Like newSampleTrack's tracks it counts
+// what it sends and the feedback it gets into stats.
+type rtpTrack struct {
+	*webrtc.TrackLocalStaticRTP
+	provider  lksdk.SampleProvider
+	payloader rtp.Payloader
+	stats     *publishedStats
+
+	lock   sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newRTPTrack(provider lksdk.SampleProvider, codec webrtc.RTPCodecCapability, payloader rtp.Payloader,
+	stats *publishedStats,
+) (*rtpTrack, error) {
+	track, err := webrtc.NewTrackLocalStaticRTP(codec, utils.NewGuid("TR_"), utils.NewGuid("ST_"))
+	if err != nil {
+		return nil, err
+	}
+	return &rtpTrack{
+		TrackLocalStaticRTP: track,
+		provider:            countSent(provider, stats, nil),
+		payloader:           payloader,
+		stats:               stats,
+	}, nil
+}
+
+// Bind starts sending once the track is negotiated, it is called by the peer connection
+func (t *rtpTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	codec, err := t.TrackLocalStaticRTP.Bind(ctx)
+	if err != nil {
+		return codec, err
+	}
+	// read feedback of the sender, interceptors require this
+	reader := ctx.RTCPReader()
+	go func() {
+		b := make([]byte, 1500)
+		for {
+			n, _, err := reader.Read(b, nil)
+			if err != nil {
+				// the sender is closed
+				return
+			}
+			pkts, err := rtcp.Unmarshal(b[:n])
+			if err != nil {
+				continue
+			}
+			for _, pkt := range pkts {
+				t.stats.onRTCP(pkt)
+			}
+		}
+	}()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.cancel != nil {
+		return codec, nil
+	}
+	if err := t.provider.OnBind(); err != nil {
+		return codec, err
+	}
+	writeCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	packetizer := rtp.NewPacketizer(rtpOutboundMTU, 0, 0, t.payloader, rtp.NewRandomSequencer(), codec.ClockRate)
+	go t.write(writeCtx, packetizer, codec.ClockRate)
+
+	return codec, nil
+}
+
+// Unbind stops sending, it is called by the peer connection when the track is removed
+func (t *rtpTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	t.lock.Lock()
+	cancel := t.cancel
+	t.cancel = nil
+	t.lock.Unlock()
+
+	if cancel != nil {
+		cancel()
+		if err := t.provider.OnUnbind(); err != nil {
+			return err
+		}
+	}
+	return t.TrackLocalStaticRTP.Unbind(ctx)
+}
+
+// write sends samples at the pace of their durations, like the SDK's LocalSampleTrack
+func (t *rtpTrack) write(ctx context.Context, packetizer rtp.Packetizer, clockRate uint32) {
+	next := time.Now()
+	for {
+		sample, err := t.provider.NextSample()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			logger.Errorw("could not get sample from provider", err)
+			return
+		}
+
+		for _, pkt := range packetizer.Packetize(sample.Data, uint32(sample.Duration.Seconds()*float64(clockRate))) {
+			if err := t.WriteRTP(pkt); err != nil {
+				logger.Errorw("could not write rtp packet", err)
+				return
+			}
+			t.stats.packets.Inc()
+		}
+
+		next = next.Add(sample.Duration)
+		select {
+		case <-time.After(time.Until(next)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
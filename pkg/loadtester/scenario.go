@@ -0,0 +1,356 @@
+package loadtester
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/livekit-cli/pkg/provider"
+)
+
+// ScenarioVersion is the only scenario document version understood by this build
+const ScenarioVersion = 1
+
+// Scenario is a declarative description of a load test, loaded from YAML.
+// It is converted into the same Params that are otherwise built from CLI flags.
+type Scenario struct {
+	Version          int           `yaml:"version"`
+	Room             string        `yaml:"room"`
+	IdentityPrefix   string        `yaml:"identity_prefix"`
+	Duration         time.Duration `yaml:"duration"`
+	NumPerSecond     float64       `yaml:"num_per_second"`
+	SameRoom         bool          `yaml:"same_room"`
+	SimulateSpeakers bool          `yaml:"simulate_speakers"`
+	Audio            bool          `yaml:"audio"`
+	// directory with a manifest.yaml of the videos to publish, instead of the built-in ones
+	MediaDir string `yaml:"media_dir"`
+	// NTP server or coordinator URL whose clock latencies are measured with
+	ClockReference string `yaml:"clock_reference"`
+	// tracks are also measured per interval of this length, e.g. 10s
+	Interval time.Duration `yaml:"interval"`
+	// stats are reset after the warm-up, which is reported as a phase when report_warm_up is set
+	WarmUp       time.Duration `yaml:"warm_up"`
+	ReportWarmUp bool          `yaml:"report_warm_up"`
+	// speaker, 3x3, 4x4 or 5x5, only used together with same_room
+	Layout      string              `yaml:"layout"`
+	Publishers  ScenarioPublishers  `yaml:"publishers"`
+	RemoteRooms ScenarioRange       `yaml:"remote_rooms"`
+	Subscribers ScenarioSubscribers `yaml:"subscribers"`
+	Data        ScenarioData        `yaml:"data"`
+	// staged subscriber load, subscribers.count is ignored when phases are set
+	Phases     []Phase            `yaml:"phases"`
+	Churn      ScenarioChurn      `yaml:"churn"`
+	Thresholds ScenarioThresholds `yaml:"thresholds"`
+}
+
+type ScenarioRange struct {
+	Start int `yaml:"start"`
+	End   int `yaml:"end"`
+}
+
+type ScenarioPublishers struct {
+	// either a plain count, or a start/end range used to shard publishers across machines
+	Count         int `yaml:"count"`
+	ScenarioRange `yaml:",inline"`
+	// one resolution per publisher, missing entries default to 1080p
+	Resolutions []string `yaml:"resolutions"`
+	Codec       string   `yaml:"codec"`
+	// publishers take turns using these codecs, e.g. [vp8, h264] for a mixed-codec room
+	Codecs []string `yaml:"codecs"`
+	// simulcast is enabled unless explicitly disabled
+	Simulcast *bool `yaml:"simulcast"`
+	// publishers send synthetic frames instead of video when set
+	Synthetic *ScenarioSynthetic `yaml:"synthetic"`
+}
+
+type ScenarioSynthetic struct {
+	// default 2000 kbps at 30 fps
+	BitrateKbps int `yaml:"bitrate_kbps"`
+	FPS         int `yaml:"fps"`
+}
+
+type ScenarioSubscribers struct {
+	// subscribers per publisher (or per room when same_room is set)
+	Count  int `yaml:"count"`
+	High   int `yaml:"high"`
+	Medium int `yaml:"medium"`
+	Low    int `yaml:"low"`
+}
+
+type ScenarioData struct {
+	Publishers  int `yaml:"publishers"`
+	PacketBytes int `yaml:"packet_bytes"`
+	BitrateKbps int `yaml:"bitrate_kbps"`
+}
+
+type ScenarioChurn struct {
+	// replacements per minute
+	SubscriberRate float64 `yaml:"subscriber_rate"`
+	PublisherRate  float64 `yaml:"publisher_rate"`
+	// mean lifetime, used when the rate is not set
+	SubscriberLifetime time.Duration `yaml:"subscriber_lifetime"`
+	PublisherLifetime  time.Duration `yaml:"publisher_lifetime"`
+}
+
+type ScenarioThresholds struct {
+	MaxLatency     time.Duration `yaml:"max_latency"`
+	MaxDropPercent *float64      `yaml:"max_drop_percent"`
+	// kind => minimum received / published bitrate, e.g. video: 0.8
+	MinBitrateRatio map[string]float64 `yaml:"min_bitrate_ratio"`
+	MaxErrors       *int               `yaml:"max_errors"`
+}
+
+// LoadScenario reads and validates a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ParseScenario(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// ParseScenario decodes a scenario document, rejecting unknown fields
+func ParseScenario(content []byte) (*Scenario, error) {
+	s := &Scenario{}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Scenario) Validate() error {
+	if s.Version != ScenarioVersion {
+		return fmt.Errorf("unsupported scenario version %d, expected %d", s.Version, ScenarioVersion)
+	}
+
+	if s.Duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+
+	if s.Interval < 0 {
+		return errors.New("interval cannot be negative")
+	}
+
+	if s.WarmUp < 0 {
+		return errors.New("warm_up cannot be negative")
+	}
+
+	if s.WarmUp > 0 && len(s.Phases) > 0 {
+		return errors.New("warm_up cannot be combined with phases")
+	}
+
+	if s.NumPerSecond < 0 {
+		return errors.New("num_per_second cannot be negative")
+	}
+
+	pub := s.Publishers
+	if pub.Count < 0 || pub.Start < 0 || pub.End < 0 {
+		return errors.New("publishers cannot be negative")
+	}
+
+	if pub.Count > 0 && (pub.Start > 0 || pub.End > 0) {
+		return errors.New("publishers.count cannot be combined with publishers.start/end")
+	}
+
+	if pub.Start > pub.End {
+		return fmt.Errorf("publishers.start (%d) is after publishers.end (%d)", pub.Start, pub.End)
+	}
+
+	if s.RemoteRooms.Start < 0 || s.RemoteRooms.End < 0 {
+		return errors.New("remote_rooms cannot be negative")
+	}
+
+	if s.RemoteRooms.Start > s.RemoteRooms.End {
+		return fmt.Errorf("remote_rooms.start (%d) is after remote_rooms.end (%d)", s.RemoteRooms.Start, s.RemoteRooms.End)
+	}
+
+	hasPublishers := pub.Count > 0 || pub.End > 0
+	hasRemote := s.RemoteRooms.End > 0
+	if hasPublishers && hasRemote {
+		return errors.New("cannot have both publishers and remote_rooms")
+	}
+
+	if !hasPublishers && !hasRemote {
+		return errors.New("either publishers or remote_rooms must be set")
+	}
+
+	ratios := provider.GetVideoResolution
+	if s.MediaDir != "" {
+		m, err := provider.LoadManifest(s.MediaDir)
+		if err != nil {
+			return err
+		}
+		ratios = m.Ratios
+	}
+	for _, r := range pub.Resolutions {
+		if ratios(r) == nil {
+			return fmt.Errorf("unsupported resolution %s", r)
+		}
+	}
+
+	if layout, err := ParseLayout(s.Layout); err != nil {
+		return err
+	} else if layout != LayoutNone && !s.SameRoom {
+		return errors.New("layout can only be used together with same_room")
+	}
+
+	if pub.Codec != "" && len(pub.Codecs) > 0 {
+		return errors.New("publishers.codec cannot be combined with publishers.codecs")
+	}
+	for _, c := range append([]string{pub.Codec}, pub.Codecs...) {
+		if err := provider.CheckCodec(strings.ToLower(c)); err != nil {
+			return err
+		}
+	}
+
+	if pub.Synthetic != nil && (pub.Synthetic.BitrateKbps < 0 || pub.Synthetic.FPS < 0) {
+		return errors.New("publishers.synthetic values cannot be negative")
+	}
+
+	sub := s.Subscribers
+	if sub.Count < 0 || sub.High < 0 || sub.Medium < 0 || sub.Low < 0 {
+		return errors.New("subscribers cannot be negative")
+	}
+
+	if sub.High+sub.Medium+sub.Low > sub.Count {
+		return fmt.Errorf("subscribers high/medium/low (%d) exceed subscribers.count (%d)",
+			sub.High+sub.Medium+sub.Low, sub.Count)
+	}
+
+	if s.Data.Publishers < 0 || s.Data.PacketBytes < 0 || s.Data.BitrateKbps < 0 {
+		return errors.New("data values cannot be negative")
+	}
+
+	maxSubscribers := sub.Count
+	for _, phase := range s.Phases {
+		if err := phase.Validate(); err != nil {
+			return err
+		}
+	}
+	if len(s.Phases) > 0 {
+		maxSubscribers = maxPhaseSubscribers(s.Phases)
+	}
+
+	if s.Data.Publishers > maxSubscribers {
+		return fmt.Errorf("data.publishers (%d) exceed subscribers (%d)", s.Data.Publishers, maxSubscribers)
+	}
+
+	c := s.Churn
+	if c.SubscriberRate < 0 || c.PublisherRate < 0 || c.SubscriberLifetime < 0 || c.PublisherLifetime < 0 {
+		return errors.New("churn values cannot be negative")
+	}
+
+	if len(s.Phases) > 0 && ChurnParams(c).enabled() {
+		return errors.New("churn cannot be combined with phases")
+	}
+
+	th := s.Thresholds
+	if th.MaxLatency < 0 || (th.MaxDropPercent != nil && *th.MaxDropPercent < 0) || (th.MaxErrors != nil && *th.MaxErrors < 0) {
+		return errors.New("thresholds cannot be negative")
+	}
+	for kind, ratio := range th.MinBitrateRatio {
+		if _, _, err := ParseBitrateRatio(fmt.Sprintf("%s:%g", kind, ratio)); err != nil {
+			return fmt.Errorf("thresholds.min_bitrate_ratio: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Params converts the scenario into load test parameters.
+// Connection details (URL, API key and secret) are left for the caller to fill in.
+func (s *Scenario) Params() Params {
+	resolution := strings.Join(s.Publishers.Resolutions, " ")
+	if resolution == "" && s.MediaDir == "" {
+		resolution = "1080p"
+	}
+
+	codec := s.Publishers.Codec
+	if len(s.Publishers.Codecs) > 0 {
+		codec = strings.Join(s.Publishers.Codecs, " ")
+	}
+
+	simulcast := true
+	if s.Publishers.Simulcast != nil {
+		simulcast = *s.Publishers.Simulcast
+	}
+
+	// already checked by Validate
+	layout, _ := ParseLayout(s.Layout)
+
+	var syntheticBitrate, syntheticFPS int
+	if s.Publishers.Synthetic != nil {
+		syntheticBitrate = s.Publishers.Synthetic.BitrateKbps * 1024
+		syntheticFPS = s.Publishers.Synthetic.FPS
+	}
+
+	var bitrateRatios map[TrackKind]float64
+	for kind, ratio := range s.Thresholds.MinBitrateRatio {
+		if bitrateRatios == nil {
+			bitrateRatios = make(map[TrackKind]float64)
+		}
+		bitrateRatios[TrackKind(kind)] = ratio
+	}
+
+	return Params{
+		VideoPublishers:       s.Publishers.Count,
+		StartPublisher:        s.Publishers.Start,
+		EndPublisher:          s.Publishers.End,
+		StartRemoteRoomNumber: s.RemoteRooms.Start,
+		EndRemoteRoomNumber:   s.RemoteRooms.End,
+		Subscribers:           s.Subscribers.Count,
+		DataPublishers:        s.Data.Publishers,
+		VideoResolution:       resolution,
+		VideoCodec:            strings.ToLower(codec),
+		Duration:              s.Duration,
+		NumPerSecond:          s.NumPerSecond,
+		Simulcast:             simulcast,
+		SameRoom:              s.SameRoom,
+		SimulateSpeakers:      s.SimulateSpeakers,
+		WithAudio:             s.Audio,
+		HighQualityViewer:     s.Subscribers.High,
+		MediumQualityView:     s.Subscribers.Medium,
+		LowQualityViewer:      s.Subscribers.Low,
+		DataPacketByteSize:    s.Data.PacketBytes,
+		DataBitrate:           s.Data.BitrateKbps * 1024,
+		Phases:                s.Phases,
+		Interval:              s.Interval,
+		WarmUp:                s.WarmUp,
+		ReportWarmUp:          s.ReportWarmUp,
+		MediaDir:              s.MediaDir,
+		ClockReference:        s.ClockReference,
+		Synthetic:             s.Publishers.Synthetic != nil,
+		SyntheticBitrate:      syntheticBitrate,
+		SyntheticFPS:          syntheticFPS,
+		Churn:                 ChurnParams(s.Churn),
+		Thresholds: Thresholds{
+			MaxLatency:      s.Thresholds.MaxLatency,
+			MaxDropPercent:  s.Thresholds.MaxDropPercent,
+			MinBitrateRatio: bitrateRatios,
+			MaxErrors:       s.Thresholds.MaxErrors,
+		},
+		TesterParams: TesterParams{
+			Room:           s.Room,
+			IdentityPrefix: s.IdentityPrefix,
+			Layout:         layout,
+		},
+	}
+}
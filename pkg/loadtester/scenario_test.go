@@ -0,0 +1,124 @@
+package loadtester
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScenario(t *testing.T) {
+	s, err := ParseScenario([]byte(`
+version: 1
+room: VM1
+duration: 90s
+publishers:
+  start: 1
+  end: 2
+  resolutions: ["1080p", "720p"]
+  codecs: [VP8, h264]
+  simulcast: false
+subscribers:
+  count: 3
+  high: 1
+  low: 1
+data:
+  publishers: 1
+  bitrate_kbps: 512
+phases:
+  - {name: ramp-up, duration: 2m, subscribers: 4}
+  - {name: hold, duration: 1m, subscribers: 4}
+`))
+	require.NoError(t, err)
+
+	params := s.Params()
+	require.Equal(t, "VM1", params.Room)
+	require.Equal(t, 90*time.Second, params.Duration)
+	require.Equal(t, 1, params.StartPublisher)
+	require.Equal(t, 2, params.EndPublisher)
+	require.Equal(t, "1080p 720p", params.VideoResolution)
+	require.Equal(t, "vp8 h264", params.VideoCodec)
+	require.False(t, params.Simulcast)
+	require.Equal(t, 3, params.Subscribers)
+	require.Equal(t, 1, params.HighQualityViewer)
+	require.Equal(t, 1, params.LowQualityViewer)
+	require.Equal(t, 512*1024, params.DataBitrate)
+	require.Equal(t, []Phase{
+		{Name: "ramp-up", Duration: 2 * time.Minute, Subscribers: 4},
+		{Name: "hold", Duration: time.Minute, Subscribers: 4},
+	}, params.Phases)
+}
+
+func TestParsePhase(t *testing.T) {
+	p, err := ParsePhase("ramp-down:5m:0")
+	require.NoError(t, err)
+	require.Equal(t, Phase{Name: "ramp-down", Duration: 5 * time.Minute}, p)
+
+	for _, s := range []string{"hold:10m", "hold:0s:10", "hold:10m:-1", "hold:ten:10"} {
+		_, err = ParsePhase(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestParseScenarioInvalid(t *testing.T) {
+	cases := map[string]string{
+		"version":        "version: 2\npublishers: {count: 1}",
+		"unknown field":  "version: 1\npublishers: {count: 1}\nsubscriber: {count: 1}",
+		"no publishers":  "version: 1\nsubscribers: {count: 1}",
+		"both":           "version: 1\npublishers: {count: 1}\nremote_rooms: {start: 1, end: 2}",
+		"range":          "version: 1\npublishers: {start: 3, end: 2}",
+		"resolution":     "version: 1\npublishers: {count: 1, resolutions: [4k]}",
+		"quality split":  "version: 1\npublishers: {count: 1}\nsubscribers: {count: 1, high: 1, low: 1}",
+		"data publisher": "version: 1\npublishers: {count: 1}\ndata: {publishers: 1}",
+		"codec":          "version: 1\npublishers: {count: 1, codecs: [vp8, av1]}",
+		"synthetic":      "version: 1\npublishers: {count: 1, synthetic: {bitrate_kbps: -1}}",
+		"warm-up phases": "version: 1\npublishers: {count: 1}\nwarm_up: 30s\nphases: [{subscribers: 1, duration: 1m}]",
+		"layout":         "version: 1\npublishers: {count: 1}\nlayout: 3x3",
+	}
+
+	for name, doc := range cases {
+		_, err := ParseScenario([]byte(doc))
+		require.Error(t, err, name)
+	}
+}
+
+func TestParseScenarioMediaDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "portrait_960.h264"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "portrait_540.h264"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(`
+videos:
+  - resolution: 540p-portrait
+    codec: h264
+    layers:
+      - {file: portrait_960.h264, width: 540, height: 960, bitrate: 1500, fps: 30}
+      - {file: portrait_540.h264, width: 304, height: 540, bitrate: 500, fps: 30}
+`), 0644))
+
+	s, err := ParseScenario([]byte("version: 1\nmedia_dir: " + dir + "\npublishers: {count: 1, resolutions: [540p-portrait]}"))
+	require.NoError(t, err)
+	params := s.Params()
+	require.Equal(t, dir, params.MediaDir)
+	require.Equal(t, "540p-portrait", params.VideoResolution)
+
+	// built-in resolutions are not available with a manifest
+	_, err = ParseScenario([]byte("version: 1\nmedia_dir: " + dir + "\npublishers: {count: 1, resolutions: [1080p]}"))
+	require.Error(t, err)
+
+	// files have to exist
+	require.NoError(t, os.Remove(filepath.Join(dir, "portrait_540.h264")))
+	_, err = ParseScenario([]byte("version: 1\nmedia_dir: " + dir + "\npublishers: {count: 1}"))
+	require.Error(t, err)
+}
+
+func TestParseScenarioSynthetic(t *testing.T) {
+	s, err := ParseScenario([]byte("version: 1\npublishers: {count: 1, synthetic: {bitrate_kbps: 5000}}"))
+	require.NoError(t, err)
+
+	params := NewLoadTest(s.Params()).Params
+	require.True(t, params.Synthetic)
+	require.Equal(t, 5000*1024, params.SyntheticBitrate)
+	require.Equal(t, defaultSyntheticFPS, params.SyntheticFPS)
+}
@@ -0,0 +1,318 @@
+package loadtester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+// SearchMode decides how the load is raised between capacity search steps
+type SearchMode string
+
+const (
+	// SearchStep raises the load by Step until a step fails or Max is reached
+	SearchStep SearchMode = "step"
+	// SearchBinary bisects between Start and Max until the bounds are within Step of each other
+	SearchBinary SearchMode = "binary"
+)
+
+// SearchTarget is the participant count that is changed between steps
+type SearchTarget string
+
+const (
+	SearchSubscribers SearchTarget = "subscribers"
+	SearchPublishers  SearchTarget = "publishers"
+)
+
+// SearchParams configure a capacity search. Every step is a regular test of Params.Duration,
+// judged against Params.Thresholds.
+type SearchParams struct {
+	Mode   SearchMode
+	Target SearchTarget
+	Start  int
+	Step   int
+	Max    int
+	// time to wait between steps, for the server to settle
+	CoolDown time.Duration
+}
+
+func ParseSearchMode(s string) (SearchMode, error) {
+	switch m := SearchMode(s); m {
+	case SearchStep, SearchBinary:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported search mode %s, choose from step, binary", s)
+	}
+}
+
+func ParseSearchTarget(s string) (SearchTarget, error) {
+	switch t := SearchTarget(s); t {
+	case "":
+		return SearchSubscribers, nil
+	case SearchSubscribers, SearchPublishers:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unsupported search target %s, choose from subscribers, publishers", s)
+	}
+}
+
+func (p SearchParams) Validate() error {
+	if _, err := ParseSearchMode(string(p.Mode)); err != nil {
+		return err
+	}
+
+	if _, err := ParseSearchTarget(string(p.Target)); err != nil {
+		return err
+	}
+
+	if p.Start <= 0 || p.Step <= 0 {
+		return errors.New("search start and step must be positive")
+	}
+
+	if p.Max < p.Start {
+		return fmt.Errorf("search max (%d) is below start (%d)", p.Max, p.Start)
+	}
+
+	return nil
+}
+
+// searchStep is the outcome of one load level
+type searchStep struct {
+	load      int
+	summaries []*summary
+	rooms     map[string][]*summary
+	checks    []*thresholdCheck
+	export    *Export
+	err       error
+}
+
+func (s *searchStep) passed() bool {
+	return s.err == nil && thresholdError(s.checks) == nil
+}
+
+// RunSearch looks for the highest load that still meets the thresholds, running one test per step
+func (t *LoadTest) RunSearch(ctx context.Context, sp SearchParams) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+
+	if !t.Params.Thresholds.enabled() {
+		return errors.New("capacity search needs at least one threshold to judge steps")
+	}
+
+	if t.Params.Duration == 0 {
+		return errors.New("capacity search needs a duration for every step")
+	}
+
+	if len(t.Params.Phases) > 0 || t.Params.Churn.enabled() {
+		return errors.New("capacity search cannot be combined with phases or churn")
+	}
+
+	if sp.Target == "" {
+		sp.Target = SearchSubscribers
+	}
+
+	var steps []*searchStep
+	export := newExport(t.Params, nil, nil, time.Now(), time.Time{})
+	run := func(load int) (bool, error) {
+		if len(steps) > 0 && sp.CoolDown > 0 {
+			fmt.Fprintf(t.Out, "\nCooling down for %s\n", sp.CoolDown)
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(sp.CoolDown):
+			}
+		}
+
+		fmt.Fprintf(t.Out, "\n=== Step %d: %d %s ===\n", len(steps)+1, load, sp.Target)
+		step := t.runSearchStep(ctx, sp.Target, load)
+		steps = append(steps, step)
+		if step.export != nil {
+			export.addRooms(fmt.Sprintf("step %d (%d %s)/", len(steps), load, sp.Target), step.export)
+		}
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		fmt.Fprintf(t.Out, "\rStep %d with %d %s %s                   \n", len(steps), load, sp.Target, stepResult(step))
+
+		return step.passed(), nil
+	}
+
+	capacity, err := searchCapacity(sp, run)
+	if err == nil && capacity == 0 && len(steps) > 0 {
+		// even the first step failed
+		if steps[0].err != nil {
+			err = fmt.Errorf("first search step failed: %w", steps[0].err)
+		} else {
+			err = thresholdError(steps[0].checks)
+		}
+	}
+
+	t.printSearchResults(sp, steps, capacity)
+
+	export.Metadata.EndedAt = time.Now()
+	if err := writeExport(t.Out, export, t.Params.Output, t.Params.OutputFile); err != nil {
+		return err
+	}
+
+	if t.Params.JUnitFile != "" {
+		rooms := make(map[string][]*summary)
+		var checks []*thresholdCheck
+		for i, step := range steps {
+			prefix := fmt.Sprintf("step %d (%d %s)/", i+1, step.load, sp.Target)
+			for room, s := range step.rooms {
+				rooms[prefix+room] = s
+			}
+			for _, c := range step.checks {
+				prefixed := *c
+				prefixed.room = prefix + c.room
+				checks = append(checks, &prefixed)
+			}
+		}
+		if err := writeJUnit(t.Params.JUnitFile, "capacity-search", rooms, checks); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// searchCapacity returns the highest load for which run passed, zero when even the start failed
+func searchCapacity(sp SearchParams, run func(load int) (bool, error)) (int, error) {
+	capacity := 0
+	switch sp.Mode {
+	case SearchStep:
+		for load := sp.Start; load <= sp.Max; load += sp.Step {
+			ok, err := run(load)
+			if err != nil || !ok {
+				return capacity, err
+			}
+			capacity = load
+		}
+
+	case SearchBinary:
+		ok, err := run(sp.Start)
+		if err != nil || !ok {
+			return capacity, err
+		}
+		capacity = sp.Start
+
+		// capacity passed, failed failed (or is beyond max)
+		failed := sp.Max + 1
+		for failed-capacity > sp.Step {
+			load := capacity + (failed-capacity)/2
+			ok, err := run(load)
+			if err != nil {
+				return capacity, err
+			}
+			if ok {
+				capacity = load
+			} else {
+				failed = load
+			}
+		}
+	}
+
+	return capacity, nil
+}
+
+// stepParams are the test params at the given load
+func (t *LoadTest) stepParams(target SearchTarget, load int) Params {
+	params := t.Params
+	switch target {
+	case SearchSubscribers:
+		params.Subscribers = load
+	case SearchPublishers:
+		params.StartPublisher, params.EndPublisher = 0, 0
+		params.VideoPublishers = load
+	}
+	// limited to the subscribers of this step rather than of the first one
+	params.DataPublishers = t.dataPublishers
+
+	return NewLoadTest(params).Params
+}
+
+// runSearchStep runs a test at the given load and judges it
+func (t *LoadTest) runSearchStep(ctx context.Context, target SearchTarget, load int) *searchStep {
+	test := NewLoadTest(t.stepParams(target, load))
+	test.Out = t.Out
+	step := &searchStep{load: load}
+	result, err := test.run(ctx, test.Params)
+	if err != nil {
+		step.err = err
+		return step
+	}
+
+	data, audio := test.Params.DataPublishers > 0, test.Params.WithAudio
+	step.summaries = summarizeStats(result.stats, data, audio)
+	step.rooms = summarizeRooms(result.stats, data, audio)
+	step.checks = checkThresholds(test.Params.Thresholds, step.rooms)
+	step.export = newExport(test.Params, result.stats, nil, time.Time{}, time.Time{})
+
+	roomClient := lksdk.NewRoomServiceClient(t.Params.URL, t.Params.APIKey, t.Params.APISecret)
+	for _, room := range result.rooms {
+		if _, err := roomClient.DeleteRoom(context.Background(), &livekit.DeleteRoomRequest{Room: room}); err != nil {
+			fmt.Fprintf(t.Out, "could not delete room %s: %s\n", room, err)
+		}
+	}
+
+	return step
+}
+
+func stepResult(step *searchStep) string {
+	if step.err != nil {
+		return fmt.Sprintf("failed: %s", step.err)
+	}
+	if err := thresholdError(step.checks); err != nil {
+		return err.Error()
+	}
+	return "passed"
+}
+
+func (t *LoadTest) printSearchResults(sp SearchParams, steps []*searchStep, capacity int) {
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nCapacity search\n")
+	_, _ = fmt.Fprintf(w, "\nStep\t| %s\t| Kind\t| Tracks\t| Bitrate\t| Latency\t| Total Dropped\t| Errors\t| Result\n", sp.Target)
+
+	for i, step := range steps {
+		result := "pass"
+		if !step.passed() {
+			result = "FAIL"
+		}
+
+		if step.err != nil {
+			_, _ = fmt.Fprintf(w, "%d\t| %d\t| -\t| -\t| -\t| -\t| -\t| %s\t| %s\n", i+1, step.load, step.err, result)
+			continue
+		}
+
+		for _, s := range step.summaries {
+			if s.tracks == 0 {
+				_, _ = fmt.Fprintf(w, "%d\t| %d\t| %s\t| 0\t| -\t| -\t| -\t| %d\t| %s\n",
+					i+1, step.load, s.kind, s.errCount, result)
+				continue
+			}
+
+			sLatency, sDropped := formatStrings(s.packets, s.latency, s.latencyCount, s.dropped)
+			_, _ = fmt.Fprintf(w, "%d\t| %d\t| %s\t| %d\t| %s\t| %s\t| %s\t| %d\t| %s\n",
+				i+1, step.load, s.kind, s.tracks, formatBitrate(s.bytes, s.elapsed), sLatency, sDropped, s.errCount, result)
+		}
+	}
+	_ = w.Flush()
+
+	if capacity == 0 {
+		fmt.Fprintf(t.Out, "\nNo step met the thresholds, capacity is below %d %s\n", sp.Start, sp.Target)
+		return
+	}
+
+	perRoom := ""
+	if sp.Target == SearchSubscribers {
+		perRoom = " per room"
+	}
+	fmt.Fprintf(t.Out, "\nCapacity: %d %s%s meet the thresholds\n", capacity, sp.Target, perRoom)
+}
@@ -0,0 +1,51 @@
+package loadtester
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCapacity(t *testing.T) {
+	// everything up to 73 passes
+	var tried []int
+	run := func(load int) (bool, error) {
+		tried = append(tried, load)
+		return load <= 73, nil
+	}
+
+	capacity, err := searchCapacity(SearchParams{Mode: SearchStep, Start: 10, Step: 20, Max: 200}, run)
+	require.NoError(t, err)
+	require.Equal(t, 70, capacity)
+	require.Equal(t, []int{10, 30, 50, 70, 90}, tried)
+
+	tried = nil
+	capacity, err = searchCapacity(SearchParams{Mode: SearchBinary, Start: 10, Step: 1, Max: 200}, run)
+	require.NoError(t, err)
+	require.Equal(t, 73, capacity)
+	require.Less(t, len(tried), 10)
+
+	tried = nil
+	capacity, err = searchCapacity(SearchParams{Mode: SearchBinary, Start: 100, Step: 1, Max: 200}, run)
+	require.NoError(t, err)
+	require.Zero(t, capacity)
+	require.Equal(t, []int{100}, tried)
+}
+
+func TestSearchStepParams(t *testing.T) {
+	// subscribers start at zero, so data publishers are limited to none until the search adds subscribers
+	test := NewLoadTest(Params{VideoPublishers: 1, DataPublishers: 5})
+	require.Zero(t, test.Params.DataPublishers)
+
+	params := test.stepParams(SearchSubscribers, 3)
+	require.Equal(t, 3, params.Subscribers)
+	require.Equal(t, 3, params.DataPublishers)
+
+	params = test.stepParams(SearchSubscribers, 10)
+	require.Equal(t, 10, params.Subscribers)
+	require.Equal(t, 5, params.DataPublishers)
+
+	params = test.stepParams(SearchPublishers, 4)
+	require.Equal(t, 4, params.VideoPublishers)
+	require.Zero(t, params.DataPublishers)
+}
@@ -54,7 +54,10 @@ func (s *SpeakerSimulator) worker() {
 			return
 		case <-t.C:
 			speaker := s.params.Testers[rand.Intn(len(s.params.Testers))]
-			speaker.room.Simulate(lksdk.SimulateSpeakerUpdate)
+			// testers may have left, e.g. when churning
+			if speaker.IsRunning() {
+				speaker.room.Simulate(lksdk.SimulateSpeakerUpdate)
+			}
 			t.Reset(time.Duration(s.params.Pause+lksdk.SimulateSpeakerUpdateInterval) * time.Second)
 		}
 	}
@@ -10,6 +10,12 @@ type testerStats struct {
 	expectedTracks int
 	stats          map[string]*trackStats
 	err            error
+	// zero duration when the tester never joined
+	joinDuration time.Duration
+	joinAttempts int
+	milestones   JoinMilestones
+	// tracks and simulcast layers the tester published
+	published []*PublishedResult
 }
 
 type TrackKind string
@@ -24,11 +30,43 @@ type trackStats struct {
 	trackID      string
 	kind         TrackKind
 	startedAt    atomic.Time
+	endedAt      atomic.Time
 	packets      atomic.Int64
 	bytes        atomic.Int64
 	dropped      atomic.Int64
 	latency      atomic.Int64
 	latencyCount atomic.Int64
+	latencyHist  latencyHistogram
+	// send times too far off the reference clock to be used, see recordSendTime
+	latencyDiscarded atomic.Int64
+	// RTP sequence number accounting and RFC 3550 interarrival jitter (ns), see sequenceTracker
+	rtpExpected   atomic.Int64
+	rtpReceived   atomic.Int64
+	rtpDuplicates atomic.Int64
+	rtpOutOfOrder atomic.Int64
+	jitter        atomic.Int64
+	sequence      *sequenceTracker
+	// bitrate the track was published at in bps, zero when unknown
+	expectedBitrate atomic.Int64
+	// arrival of the last packet and the longest gap without packets, see recordArrival
+	lastPacketAt atomic.Time
+	longestStall atomic.Duration
+	// counters per interval when Params.Interval is set
+	intervals intervalRecorder
+	// video quality the subscriber asked for, empty when it follows a layout or the room,
+	// and the SVC layers that arrived, see layerOf
+	requested string
+	layers    svcLayers
+	// decodable video frames and the freezes between them, and the time from subscribing to the first keyframe,
+	// see frameTracker
+	frames        atomic.Int64
+	freezes       atomic.Int64
+	freezeTime    atomic.Duration
+	firstKeyframe atomic.Duration
+	frameTracker  *frameTracker
+	// simulcast layers received and the time from subscribing to the requested one, see recordDelivered
+	delivered       deliveredQualities
+	timeToRequested atomic.Duration
 }
 
 type summary struct {
@@ -42,12 +80,175 @@ type summary struct {
 	elapsed      time.Duration
 	errString    string
 	errCount     int64
+	latencyHist  *latencyHistogram
+	// bytes received on tracks with a known published bitrate, and the bytes expected for them
+	comparedBytes int64
+	expectedBytes int64
+	// range of interval bitrates over intervalTracks tracks with intervals, and the longest stall of any track
+	minIntervalBitrate float64
+	maxIntervalBitrate float64
+	intervalTracks     int
+	longestStall       time.Duration
+	// frames and freezes of video tracks that received frames, over trackTime summed up for them,
+	// and the time to the first keyframe over keyframeTracks tracks
+	frames           int64
+	freezes          int64
+	freezeTime       time.Duration
+	trackTime        time.Duration
+	firstKeyframe    time.Duration
+	keyframeTracks   int
+	maxFirstKeyframe time.Duration
 }
 
 func (k TrackKind) String() string {
 	return string(k)
 }
 
+func (s *trackStats) recordLatency(latency int64) {
+	s.latency.Add(latency)
+	s.latencyCount.Inc()
+	s.latencyHist.record(latency)
+}
+
+// rtpLost is the number of packets that never arrived, going by sequence numbers
+func (s *trackStats) rtpLost() int64 {
+	lost := s.rtpExpected.Load() - s.rtpReceived.Load()
+	if lost < 0 {
+		return 0
+	}
+	return lost
+}
+
+// elapsed returns how long the track has been measured, up to its end if it has ended
+func (s *trackStats) elapsed() time.Duration {
+	startedAt := s.startedAt.Load()
+	if startedAt.IsZero() {
+		return 0
+	}
+
+	if endedAt := s.endedAt.Load(); !endedAt.IsZero() {
+		return endedAt.Sub(startedAt)
+	}
+
+	return time.Since(startedAt)
+}
+
+// snapshot copies current counters, the copy is frozen at the time it was taken
+func (s *trackStats) snapshot() *trackStats {
+	c := &trackStats{
+		trackID:   s.trackID,
+		kind:      s.kind,
+		requested: s.requested,
+	}
+	c.startedAt.Store(s.startedAt.Load())
+	c.endedAt.Store(s.endedAt.Load())
+	if c.endedAt.Load().IsZero() {
+		c.endedAt.Store(time.Now())
+	}
+	c.packets.Store(s.packets.Load())
+	c.bytes.Store(s.bytes.Load())
+	c.dropped.Store(s.dropped.Load())
+	c.latency.Store(s.latency.Load())
+	c.latencyCount.Store(s.latencyCount.Load())
+	c.latencyHist.merge(&s.latencyHist)
+	c.latencyDiscarded.Store(s.latencyDiscarded.Load())
+	c.rtpExpected.Store(s.rtpExpected.Load())
+	c.rtpReceived.Store(s.rtpReceived.Load())
+	c.rtpDuplicates.Store(s.rtpDuplicates.Load())
+	c.rtpOutOfOrder.Store(s.rtpOutOfOrder.Load())
+	c.jitter.Store(s.jitter.Load())
+	c.expectedBitrate.Store(s.expectedBitrate.Load())
+	// the copy has no more packets coming, its stall includes the time since the last one
+	c.longestStall.Store(s.stall(c.endedAt.Load()))
+	c.setIntervals(s.intervalResults())
+	c.setLayers(s.layerResults())
+	c.frames.Store(s.frames.Load())
+	c.freezes.Store(s.freezes.Load())
+	c.freezeTime.Store(s.freezeTime.Load())
+	c.firstKeyframe.Store(s.firstKeyframe.Load())
+	c.setDelivered(s.deliveredTimes(c.endedAt.Load()))
+	c.timeToRequested.Store(s.timeToRequested.Load())
+
+	return c
+}
+
+// delta returns the counters accumulated between an earlier snapshot and this one
+func (s *trackStats) delta(prev *trackStats) *trackStats {
+	if prev == nil {
+		return s
+	}
+
+	d := &trackStats{
+		trackID:   s.trackID,
+		kind:      s.kind,
+		requested: s.requested,
+	}
+	d.startedAt.Store(prev.endedAt.Load())
+	d.endedAt.Store(s.endedAt.Load())
+	d.packets.Store(s.packets.Load() - prev.packets.Load())
+	d.bytes.Store(s.bytes.Load() - prev.bytes.Load())
+	d.dropped.Store(s.dropped.Load() - prev.dropped.Load())
+	d.latency.Store(s.latency.Load() - prev.latency.Load())
+	d.latencyCount.Store(s.latencyCount.Load() - prev.latencyCount.Load())
+	d.latencyHist.merge(&s.latencyHist)
+	d.latencyHist.subtract(&prev.latencyHist)
+	d.latencyDiscarded.Store(s.latencyDiscarded.Load() - prev.latencyDiscarded.Load())
+	d.rtpExpected.Store(s.rtpExpected.Load() - prev.rtpExpected.Load())
+	d.rtpReceived.Store(s.rtpReceived.Load() - prev.rtpReceived.Load())
+	d.rtpDuplicates.Store(s.rtpDuplicates.Load() - prev.rtpDuplicates.Load())
+	d.rtpOutOfOrder.Store(s.rtpOutOfOrder.Load() - prev.rtpOutOfOrder.Load())
+	d.jitter.Store(s.jitter.Load())
+	d.expectedBitrate.Store(s.expectedBitrate.Load())
+	// like the latency max, the longest stall can not be undone and is kept
+	d.longestStall.Store(s.longestStall.Load())
+	var intervals []*IntervalResult
+	for _, r := range s.intervalResults() {
+		if !r.Start.Before(prev.endedAt.Load()) {
+			intervals = append(intervals, r)
+		}
+	}
+	d.setIntervals(intervals)
+	d.setLayers(subtractLayers(s.layerResults(), prev.layerResults()))
+	d.frames.Store(s.frames.Load() - prev.frames.Load())
+	d.freezes.Store(s.freezes.Load() - prev.freezes.Load())
+	d.freezeTime.Store(s.freezeTime.Load() - prev.freezeTime.Load())
+	// happens once per track, like the longest stall it is kept
+	d.firstKeyframe.Store(s.firstKeyframe.Load())
+	times, switches := s.deliveredTimes(s.endedAt.Load())
+	prevTimes, prevSwitches := prev.deliveredTimes(prev.endedAt.Load())
+	d.setDelivered(subtractDelivered(times, prevTimes), switches-prevSwitches)
+	d.timeToRequested.Store(s.timeToRequested.Load())
+
+	return d
+}
+
+// reset zeroes counters in place, so goroutines that hold the pointer keep reporting into it
+func (s *trackStats) reset() {
+	s.packets.Store(0)
+	s.bytes.Store(0)
+	s.dropped.Store(0)
+	s.latency.Store(0)
+	s.latencyCount.Store(0)
+	s.latencyHist.reset()
+	s.latencyDiscarded.Store(0)
+	s.rtpExpected.Store(0)
+	s.rtpReceived.Store(0)
+	s.rtpDuplicates.Store(0)
+	s.rtpOutOfOrder.Store(0)
+	s.lastPacketAt.Store(time.Time{})
+	s.longestStall.Store(0)
+	s.resetIntervals()
+	s.setLayers(nil)
+	// the first keyframe is part of joining, it is kept
+	s.frames.Store(0)
+	s.freezes.Store(0)
+	s.freezeTime.Store(0)
+	// like the first keyframe, the time to the requested quality is kept
+	s.resetDelivered()
+	s.endedAt.Store(time.Time{})
+	s.startedAt.Store(time.Now())
+}
+
 func getTestSummary(summaries map[string][]*summary, data, audio bool) []*summary {
 	sumTotal := []*summary{
 		getTestTotalSummary(summaries, TrackKindVideo),
@@ -65,7 +266,7 @@ func getTestSummary(summaries map[string][]*summary, data, audio bool) []*summar
 }
 
 func getTestTotalSummary(summaries map[string][]*summary, kind TrackKind) *summary {
-	s := &summary{}
+	s := &summary{kind: kind}
 	for _, testerSummary := range summaries {
 		for _, trackSummary := range testerSummary {
 			if trackSummary.kind != kind {
@@ -78,12 +279,20 @@ func getTestTotalSummary(summaries map[string][]*summary, kind TrackKind) *summa
 			s.bytes += trackSummary.bytes
 			s.latency += trackSummary.latency
 			s.latencyCount += trackSummary.latencyCount
+			s.mergeLatency(trackSummary.latencyHist)
 			s.dropped += trackSummary.dropped
 			if trackSummary.elapsed > s.elapsed {
 				s.elapsed = trackSummary.elapsed
 			}
 
 			s.errCount += trackSummary.errCount
+			s.comparedBytes += trackSummary.comparedBytes
+			s.expectedBytes += trackSummary.expectedBytes
+			s.addIntervalBitrates(trackSummary.minIntervalBitrate, trackSummary.maxIntervalBitrate, trackSummary.intervalTracks)
+			if trackSummary.longestStall > s.longestStall {
+				s.longestStall = trackSummary.longestStall
+			}
+			s.addFrames(trackSummary)
 		}
 	}
 
@@ -113,6 +322,7 @@ func getTesterTracksSummary(testerStats *testerStats, kind TrackKind) *summary {
 
 	if testerStats.err != nil {
 		return &summary{
+			kind:      kind,
 			errString: testerStats.err.Error(),
 			errCount:  1,
 		}
@@ -130,16 +340,141 @@ func getTesterTracksSummary(testerStats *testerStats, kind TrackKind) *summary {
 
 		s.tracks++
 		s.packets += trackStats.packets.Load()
-		s.packets += trackStats.packets.Load()
 		s.bytes += trackStats.bytes.Load()
 		s.dropped += trackStats.dropped.Load()
 		s.latency += trackStats.latency.Load()
 		s.latencyCount += trackStats.latencyCount.Load()
+		s.mergeLatency(&trackStats.latencyHist)
 
-		elapsed := time.Since(trackStats.startedAt.Load())
+		elapsed := trackStats.elapsed()
 		if elapsed > s.elapsed {
 			s.elapsed = elapsed
 		}
+
+		if expected := trackStats.expectedBitrate.Load(); expected > 0 {
+			s.comparedBytes += trackStats.bytes.Load()
+			s.expectedBytes += int64(float64(expected) / 8 * elapsed.Seconds())
+		}
+
+		if lowest, highest, ok := intervalBitrates(trackStats.intervalResults()); ok {
+			s.addIntervalBitrates(lowest, highest, 1)
+		}
+		if stall := trackStats.longestStall.Load(); stall > s.longestStall {
+			s.longestStall = stall
+		}
+		if frames := trackStats.frames.Load(); frames > 0 {
+			s.addFrames(&summary{
+				frames:           frames,
+				freezes:          trackStats.freezes.Load(),
+				freezeTime:       trackStats.freezeTime.Load(),
+				trackTime:        elapsed,
+				firstKeyframe:    trackStats.firstKeyframe.Load(),
+				keyframeTracks:   1,
+				maxFirstKeyframe: trackStats.firstKeyframe.Load(),
+			})
+		}
+	}
+
+	return s
+}
+
+// summarizeStats totals every kind over all rooms of a test
+func summarizeStats(stats map[string]map[string]*testerStats, data, audio bool) []*summary {
+	summaries := make(map[string][]*summary)
+	for room, roomStats := range stats {
+		for name, ts := range roomStats {
+			if len(ts.stats) == 0 && ts.err == nil {
+				continue
+			}
+			summaries[room+"/"+name] = getTesterSummary(ts, data, audio)
+		}
+	}
+
+	return getTestSummary(summaries, data, audio)
+}
+
+// summarizeRooms totals every kind per room
+func summarizeRooms(stats map[string]map[string]*testerStats, data, audio bool) map[string][]*summary {
+	rooms := make(map[string][]*summary)
+	for room, roomStats := range stats {
+		rooms[room] = summarizeStats(map[string]map[string]*testerStats{room: roomStats}, data, audio)
+	}
+
+	return rooms
+}
+
+func (s *summary) mergeLatency(h *latencyHistogram) {
+	if h == nil {
+		return
+	}
+	if s.latencyHist == nil {
+		s.latencyHist = &latencyHistogram{}
+	}
+	s.latencyHist.merge(h)
+}
+
+func (s *summary) avgLatency() time.Duration {
+	if s.latencyCount == 0 {
+		return 0
+	}
+	return time.Duration(s.latency / s.latencyCount)
+}
+
+// dropPercent is the share of packets that were dropped, in percent
+func (s *summary) dropPercent() float64 {
+	if s.packets+s.dropped == 0 {
+		return 0
+	}
+	return float64(s.dropped) / float64(s.packets+s.dropped) * 100
+}
+
+// bitrateRatio compares received bitrate to published bitrate, false when no track had a known bitrate
+func (s *summary) bitrateRatio() (float64, bool) {
+	if s.expectedBytes == 0 {
+		return 0, false
+	}
+	return float64(s.comparedBytes) / float64(s.expectedBytes), true
+}
+
+// joinSummary describes how testers of a room got in, and the distribution of every step of joinSteps
+type joinSummary struct {
+	testers int
+	joined  int
+	failed  int
+	retries int
+	// testers that reached a step, and their total and distribution of times to it
+	reached   []int
+	stepTotal []time.Duration
+	steps     []latencyHistogram
+}
+
+func getJoinSummary(stats map[string]*testerStats) *joinSummary {
+	s := &joinSummary{
+		reached:   make([]int, len(joinSteps)),
+		stepTotal: make([]time.Duration, len(joinSteps)),
+		steps:     make([]latencyHistogram, len(joinSteps)),
+	}
+	for _, ts := range stats {
+		if ts.joinAttempts == 0 {
+			continue
+		}
+
+		s.testers++
+		s.retries += ts.joinAttempts - 1
+		if ts.joinDuration == 0 {
+			s.failed++
+		} else {
+			s.joined++
+		}
+
+		for i, d := range ts.joinSteps() {
+			if d <= 0 {
+				continue
+			}
+			s.reached[i]++
+			s.stepTotal[i] += d
+			s.steps[i].record(d.Nanoseconds())
+		}
 	}
 
 	return s
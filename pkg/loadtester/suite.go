@@ -0,0 +1,218 @@
+package loadtester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+// BenchmarkCase is one test of the built-in benchmark suite
+type BenchmarkCase struct {
+	Name   string
+	Params Params
+}
+
+// BenchmarkSuite returns the fixed set of cases run by --run-all, each running for duration
+func BenchmarkSuite(duration time.Duration) []BenchmarkCase {
+	return []BenchmarkCase{
+		{
+			Name: "1:1 call",
+			Params: Params{
+				VideoPublishers:     2,
+				SameRoom:            true,
+				WithAudio:           true,
+				VideoResolution:     "720p 720p",
+				Simulcast:           true,
+				PublishersSubscribe: true,
+				Duration:            duration,
+			},
+		},
+		{
+			Name: "10-publisher meeting",
+			Params: Params{
+				VideoPublishers:  10,
+				Subscribers:      10,
+				SameRoom:         true,
+				WithAudio:        true,
+				SimulateSpeakers: true,
+				VideoResolution:  strings.TrimSpace(strings.Repeat("720p ", 10)),
+				Simulcast:        true,
+				Duration:         duration,
+				TesterParams: TesterParams{
+					Layout: LayoutSpeaker,
+				},
+			},
+		},
+		{
+			Name: "1-to-500 webinar",
+			Params: Params{
+				VideoPublishers:   1,
+				Subscribers:       500,
+				WithAudio:         true,
+				VideoResolution:   "1080p",
+				Simulcast:         true,
+				HighQualityViewer: 300,
+				MediumQualityView: 150,
+				LowQualityViewer:  50,
+				NumPerSecond:      10,
+				Duration:          duration,
+			},
+		},
+		{
+			Name: "data-heavy room",
+			Params: Params{
+				VideoPublishers:    1,
+				Subscribers:        20,
+				DataPublishers:     20,
+				DataPacketByteSize: 1024,
+				DataBitrate:        256 * 1024,
+				VideoResolution:    "360p",
+				Simulcast:          true,
+				Duration:           duration,
+			},
+		},
+	}
+}
+
+type benchmarkResult struct {
+	name      string
+	params    Params
+	summaries []*summary
+	err       error
+}
+
+// RunSuite runs the cases one after another with the connection details of t,
+// removing the rooms of every case and waiting coolDown before the next one
+func (t *LoadTest) RunSuite(ctx context.Context, cases []BenchmarkCase, coolDown time.Duration) error {
+	roomClient := lksdk.NewRoomServiceClient(t.Params.URL, t.Params.APIKey, t.Params.APISecret)
+	prefix := t.Params.Room
+	if prefix == "" {
+		prefix = "benchmark"
+	}
+
+	var results []*benchmarkResult
+	// case/room => summaries, for thresholds
+	rooms := make(map[string][]*summary)
+	export := newExport(t.Params, nil, nil, time.Now(), time.Time{})
+	for i, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if i > 0 && coolDown > 0 {
+			fmt.Fprintf(t.Out, "\nCooling down for %s\n", coolDown)
+			select {
+			case <-ctx.Done():
+			case <-time.After(coolDown):
+			}
+		}
+
+		params := c.Params
+		params.URL = t.Params.URL
+		params.APIKey = t.Params.APIKey
+		params.APISecret = t.Params.APISecret
+		params.Room = fmt.Sprintf("%s-%d", prefix, i+1)
+		params.Interval = t.Params.Interval
+		params.MediaDir = t.Params.MediaDir
+		if t.Params.MediaDir != "" {
+			// the built-in resolutions need not be in the manifest, its first video is published instead
+			params.VideoResolution = ""
+		}
+		params.Synthetic = t.Params.Synthetic
+		params.SyntheticBitrate = t.Params.SyntheticBitrate
+		params.SyntheticFPS = t.Params.SyntheticFPS
+
+		fmt.Fprintf(t.Out, "\n=== Case %d/%d: %s ===\n", i+1, len(cases), c.Name)
+		test := NewLoadTest(params)
+		test.Out = t.Out
+		res := &benchmarkResult{
+			name:   c.Name,
+			params: test.Params,
+		}
+		results = append(results, res)
+
+		result, err := test.run(ctx, test.Params)
+		if err != nil {
+			res.err = err
+			fmt.Fprintf(t.Out, "Case %s failed: %s\n", c.Name, err)
+			continue
+		}
+
+		test.printStats(result.stats)
+		test.printJoins(result.stats, result.publishers)
+		test.printPublishers(result.publishers)
+		res.summaries = summarizeStats(result.stats, test.Params.DataPublishers > 0, test.Params.WithAudio)
+		for room, s := range summarizeRooms(result.stats, test.Params.DataPublishers > 0, test.Params.WithAudio) {
+			rooms[c.Name+"/"+room] = s
+		}
+		export.addRooms(c.Name+"/", newExport(test.Params, result.stats, nil, time.Time{}, time.Time{}))
+
+		for _, room := range result.rooms {
+			if _, err := roomClient.DeleteRoom(context.Background(), &livekit.DeleteRoomRequest{Room: room}); err != nil {
+				fmt.Fprintf(t.Out, "could not delete room %s: %s\n", room, err)
+			}
+		}
+	}
+
+	printBenchmarkResults(t.Out, results)
+
+	// cases finished before a cancel are still exported
+	export.Metadata.EndedAt = time.Now()
+	if err := writeExport(t.Out, export, t.Params.Output, t.Params.OutputFile); err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if !t.Params.Thresholds.enabled() && t.Params.JUnitFile == "" {
+		return nil
+	}
+
+	checks := checkThresholds(t.Params.Thresholds, rooms)
+	printThresholds(t.Out, checks)
+	if t.Params.JUnitFile != "" {
+		if err := writeJUnit(t.Params.JUnitFile, "benchmark", rooms, checks); err != nil {
+			return err
+		}
+	}
+
+	return thresholdError(checks)
+}
+
+func printBenchmarkResults(out io.Writer, results []*benchmarkResult) {
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nBenchmark results\n")
+	_, _ = fmt.Fprint(w, "\nCase\t| Kind\t| Tracks\t| Bitrate\t| Latency\t| Total Dropped\t| Errors\n")
+
+	for _, res := range results {
+		if res.err != nil {
+			_, _ = fmt.Fprintf(w, "%s\t| -\t| -\t| -\t| -\t| -\t| %s\n", res.name, res.err)
+			continue
+		}
+
+		for _, s := range res.summaries {
+			if s.tracks == 0 {
+				_, _ = fmt.Fprintf(w, "%s\t| %s\t| 0\t| -\t| -\t| -\t| %d\n", res.name, s.kind, s.errCount)
+				continue
+			}
+
+			sLatency, sDropped := formatStrings(s.packets, s.latency, s.latencyCount, s.dropped)
+			sBitrate := fmt.Sprintf("%s (%s avg)",
+				formatBitrate(s.bytes, s.elapsed),
+				formatBitrate(s.bytes/int64(s.tracks), s.elapsed),
+			)
+			_, _ = fmt.Fprintf(w, "%s\t| %s\t| %d\t| %s\t| %s\t| %s\t| %d\n",
+				res.name, s.kind, s.tracks, sBitrate, sLatency, sDropped, s.errCount)
+		}
+	}
+
+	_ = w.Flush()
+}
@@ -0,0 +1,102 @@
+package loadtester
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkSuite(t *testing.T) {
+	cases := BenchmarkSuite(2 * time.Minute)
+	require.Len(t, cases, 4)
+
+	names := make(map[string]bool)
+	for _, c := range cases {
+		require.False(t, names[c.Name], c.Name)
+		names[c.Name] = true
+
+		params := NewLoadTest(c.Params).Params
+		require.Equal(t, 2*time.Minute, params.Duration, c.Name)
+		// every publisher has a resolution
+		require.Len(t, strings.Fields(params.VideoResolution), params.VideoPublishers, c.Name)
+		require.LessOrEqual(t, params.DataPublishers, params.Subscribers, c.Name)
+		require.LessOrEqual(t, params.HighQualityViewer+params.MediumQualityView+params.LowQualityViewer, params.Subscribers, c.Name)
+		if c.Params.Layout != LayoutNone {
+			require.True(t, params.SameRoom, c.Name)
+		}
+	}
+
+	call := cases[0].Params
+	require.Equal(t, 2, call.VideoPublishers)
+	require.Zero(t, call.Subscribers)
+	require.True(t, call.SameRoom)
+	require.True(t, call.PublishersSubscribe)
+	pub := prepareTesterPubParams(call, 0, "room", 1)
+	require.True(t, pub.Subscribe)
+
+	webinar := cases[2].Params
+	require.Equal(t, 1, webinar.VideoPublishers)
+	require.Equal(t, 500, webinar.Subscribers)
+	require.False(t, webinar.SameRoom)
+}
+
+func TestPrintBenchmarkResults(t *testing.T) {
+	elapsed := 10 * time.Second
+	results := []*benchmarkResult{
+		{
+			name: "call",
+			summaries: []*summary{
+				{kind: TrackKindVideo, tracks: 2, packets: 1000, bytes: 1_250_000, elapsed: elapsed},
+				{kind: TrackKindAudio, errCount: 1},
+			},
+		},
+		{
+			name: "webinar",
+			err:  errors.New("could not publish"),
+		},
+	}
+
+	var out bytes.Buffer
+	printBenchmarkResults(&out, results)
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(out.String(), "\n") {
+		cols := strings.Split(line, "|")
+		if len(cols) != 7 {
+			continue
+		}
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		rows[cols[0]+"/"+cols[1]] = cols[2:]
+	}
+
+	require.Equal(t, "2", rows["call/video"][0])
+	require.Equal(t, "0", rows["call/audio"][0])
+	require.Equal(t, "1", rows["call/audio"][4])
+	// failed cases show their error instead of stats
+	require.Equal(t, []string{"-", "-", "-", "-", "could not publish"}, rows["webinar/-"])
+}
+
+func TestRunSuiteCanceledExports(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	path := filepath.Join(t.TempDir(), "suite.json")
+	test := NewLoadTest(Params{Output: OutputJSON, OutputFile: path})
+	err := test.RunSuite(ctx, BenchmarkSuite(time.Minute), 0)
+	require.ErrorIs(t, err, context.Canceled)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var e Export
+	require.NoError(t, json.Unmarshal(data, &e))
+}
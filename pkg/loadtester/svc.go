@@ -0,0 +1,190 @@
+package loadtester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3"
+)
+
+// LayerResult counts the packets a track received of one spatial and temporal layer
+type LayerResult struct {
+	Spatial  int   `json:"spatial"`
+	Temporal int   `json:"temporal"`
+	Packets  int64 `json:"packets"`
+}
+
+func (r *LayerResult) String() string {
+	return fmt.Sprintf("S%dT%d", r.Spatial, r.Temporal)
+}
+
+type svcLayer struct {
+	spatial, temporal int
+}
+
+// svcLayers counts packets per layer, for VP9 tracks whose packets carry layer indices
+type svcLayers struct {
+	lock    sync.Mutex
+	packets map[svcLayer]int64
+}
+
+// layerOf reads the spatial and temporal layer of a VP9 packet, it returns false for other codecs
+// and packets without layer indices. AV1 is not negotiated by the SDK, so its tracks are not received.
+func layerOf(mimeType string, payload []byte) (svcLayer, bool) {
+	if !strings.EqualFold(mimeType, webrtc.MimeTypeVP9) {
+		return svcLayer{}, false
+	}
+
+	p := &codecs.VP9Packet{}
+	if _, err := p.Unmarshal(payload); err != nil || !p.L {
+		return svcLayer{}, false
+	}
+	return svcLayer{spatial: int(p.SID), temporal: int(p.TID)}, true
+}
+
+func (s *trackStats) recordLayer(l svcLayer) {
+	s.layers.lock.Lock()
+	defer s.layers.lock.Unlock()
+
+	if s.layers.packets == nil {
+		s.layers.packets = make(map[svcLayer]int64)
+	}
+	s.layers.packets[l]++
+}
+
+// layerResults returns packets per layer, lowest layer first
+func (s *trackStats) layerResults() []*LayerResult {
+	s.layers.lock.Lock()
+	defer s.layers.lock.Unlock()
+
+	results := make([]*LayerResult, 0, len(s.layers.packets))
+	for l, packets := range s.layers.packets {
+		results = append(results, &LayerResult{Spatial: l.spatial, Temporal: l.temporal, Packets: packets})
+	}
+	sortLayers(results)
+
+	return results
+}
+
+func (s *trackStats) setLayers(results []*LayerResult) {
+	s.layers.lock.Lock()
+	defer s.layers.lock.Unlock()
+
+	s.layers.packets = nil
+	for _, r := range results {
+		if s.layers.packets == nil {
+			s.layers.packets = make(map[svcLayer]int64)
+		}
+		s.layers.packets[svcLayer{spatial: r.Spatial, temporal: r.Temporal}] += r.Packets
+	}
+}
+
+// subtractLayers returns the packets per layer of current that are not in prev
+func subtractLayers(current, prev []*LayerResult) []*LayerResult {
+	before := make(map[svcLayer]int64, len(prev))
+	for _, r := range prev {
+		before[svcLayer{spatial: r.Spatial, temporal: r.Temporal}] = r.Packets
+	}
+
+	var results []*LayerResult
+	for _, r := range current {
+		if packets := r.Packets - before[svcLayer{spatial: r.Spatial, temporal: r.Temporal}]; packets > 0 {
+			results = append(results, &LayerResult{Spatial: r.Spatial, Temporal: r.Temporal, Packets: packets})
+		}
+	}
+
+	return results
+}
+
+func sortLayers(results []*LayerResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Spatial != results[j].Spatial {
+			return results[i].Spatial < results[j].Spatial
+		}
+		return results[i].Temporal < results[j].Temporal
+	})
+}
+
+// printLayers shows which SVC layers subscribers received per room and requested quality.
+// Nothing is printed unless some track carried layer indices.
+func (t *LoadTest) printLayers(stats map[string]map[string]*testerStats) {
+	type layerKey struct {
+		room, requested string
+	}
+	received := make(map[layerKey][]*LayerResult)
+	tracks := make(map[layerKey]int)
+	for room, roomStats := range stats {
+		for _, ts := range roomStats {
+			for _, s := range ts.stats {
+				layers := s.layerResults()
+				if len(layers) == 0 {
+					continue
+				}
+				key := layerKey{room: room, requested: s.requested}
+				if key.requested == "" {
+					key.requested = "-"
+				}
+				received[key] = mergeLayers(received[key], layers)
+				tracks[key]++
+			}
+		}
+	}
+	if len(received) == 0 {
+		return
+	}
+
+	keys := make([]layerKey, 0, len(received))
+	for key := range received {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].room != keys[j].room {
+			return keys[i].room < keys[j].room
+		}
+		return keys[i].requested < keys[j].requested
+	})
+
+	w := tabwriter.NewWriter(t.Out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nSVC Layers\t| Room\t| Requested\t| Tracks\t| Highest\t| Packets per Layer\n")
+	for _, key := range keys {
+		layers := received[key]
+		highest := svcLayer{}
+		perLayer := make([]string, 0, len(layers))
+		for _, l := range layers {
+			if l.Spatial > highest.spatial {
+				highest.spatial = l.Spatial
+			}
+			if l.Temporal > highest.temporal {
+				highest.temporal = l.Temporal
+			}
+			perLayer = append(perLayer, fmt.Sprintf("%s %d", l, l.Packets))
+		}
+		_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %d\t| S%dT%d\t| %s\n",
+			key.room, key.requested, tracks[key], highest.spatial, highest.temporal, strings.Join(perLayer, ", "))
+	}
+	_ = w.Flush()
+}
+
+// mergeLayers adds the packets of more to layers
+func mergeLayers(layers, more []*LayerResult) []*LayerResult {
+	for _, m := range more {
+		found := false
+		for _, l := range layers {
+			if l.Spatial == m.Spatial && l.Temporal == m.Temporal {
+				l.Packets += m.Packets
+				found = true
+				break
+			}
+		}
+		if !found {
+			layers = append(layers, &LayerResult{Spatial: m.Spatial, Temporal: m.Temporal, Packets: m.Packets})
+		}
+	}
+	sortLayers(layers)
+
+	return layers
+}
@@ -0,0 +1,43 @@
+package loadtester
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayerOf(t *testing.T) {
+	// VP9 with I=0, L=1, B=1: TID 2, U=0, SID 1, D=0 and TL0PICIDX
+	l, ok := layerOf("video/VP9", []byte{0x28, 0x42, 0x00, 0xaa})
+	require.True(t, ok)
+	require.Equal(t, svcLayer{spatial: 1, temporal: 2}, l)
+
+	// VP9 without layer indices
+	_, ok = layerOf("video/VP9", []byte{0x08, 0xaa})
+	require.False(t, ok)
+
+	_, ok = layerOf("video/VP8", []byte{0x10, 0x00})
+	require.False(t, ok)
+}
+
+func TestLayerStats(t *testing.T) {
+	s := &trackStats{trackID: "TR_1", kind: TrackKindVideo, requested: "low"}
+	s.recordLayer(svcLayer{spatial: 0, temporal: 1})
+	s.recordLayer(svcLayer{spatial: 0, temporal: 0})
+	s.recordLayer(svcLayer{spatial: 0, temporal: 0})
+
+	prev := s.snapshot()
+	require.Equal(t, []*LayerResult{
+		{Spatial: 0, Temporal: 0, Packets: 2},
+		{Spatial: 0, Temporal: 1, Packets: 1},
+	}, prev.layerResults())
+	require.Equal(t, "low", prev.requested)
+
+	s.recordLayer(svcLayer{spatial: 1, temporal: 0})
+	require.Equal(t, []*LayerResult{
+		{Spatial: 1, Temporal: 0, Packets: 1},
+	}, s.snapshot().delta(prev).layerResults())
+
+	s.reset()
+	require.Empty(t, s.layerResults())
+}
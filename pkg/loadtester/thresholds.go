@@ -0,0 +1,214 @@
+package loadtester
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Thresholds are service level objectives checked against the summary of every room.
+// Zero values (or nil pointers) are not checked.
+type Thresholds struct {
+	MaxLatency     time.Duration
+	MaxDropPercent *float64
+	// minimum received / published bitrate for video and data, only for tracks with a known published bitrate
+	MinBitrateRatio map[TrackKind]float64
+	// testers that failed, per room
+	MaxErrors *int
+}
+
+func (t Thresholds) enabled() bool {
+	return t.MaxLatency > 0 || t.MaxDropPercent != nil || len(t.MinBitrateRatio) > 0 || t.MaxErrors != nil
+}
+
+// ParseBitrateRatio parses kind:ratio, e.g. video:0.8
+func ParseBitrateRatio(s string) (TrackKind, float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid bitrate ratio %s, expected kind:ratio", s)
+	}
+
+	kind := TrackKind(parts[0])
+	switch kind {
+	case TrackKindVideo, TrackKindData:
+	case TrackKindAudio:
+		// audio is published without a target bitrate to compare against
+		return "", 0, fmt.Errorf("bitrate ratio cannot be checked for audio, choose from video, data")
+	default:
+		return "", 0, fmt.Errorf("invalid kind %s, choose from video, data", parts[0])
+	}
+
+	ratio, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || ratio < 0 {
+		return "", 0, fmt.Errorf("invalid ratio %s", parts[1])
+	}
+
+	return kind, ratio, nil
+}
+
+// ThresholdError is returned when a test ran but did not meet its thresholds
+type ThresholdError struct {
+	Failures []string
+}
+
+func (e *ThresholdError) Error() string {
+	return fmt.Sprintf("%d threshold(s) failed:\n  %s", len(e.Failures), strings.Join(e.Failures, "\n  "))
+}
+
+// thresholdCheck is the outcome of one threshold for one room and kind
+type thresholdCheck struct {
+	room   string
+	name   string
+	value  string
+	limit  string
+	failed bool
+}
+
+func (c *thresholdCheck) String() string {
+	return fmt.Sprintf("%s: %s is %s, limit %s", c.room, c.name, c.value, c.limit)
+}
+
+// checkThresholds evaluates the thresholds against the per-room summaries, in room order
+func checkThresholds(th Thresholds, rooms map[string][]*summary) []*thresholdCheck {
+	names := make([]string, 0, len(rooms))
+	for room := range rooms {
+		names = append(names, room)
+	}
+	sort.Strings(names)
+
+	ratioKinds := make([]TrackKind, 0, len(th.MinBitrateRatio))
+	for kind := range th.MinBitrateRatio {
+		ratioKinds = append(ratioKinds, kind)
+	}
+	sort.Slice(ratioKinds, func(i, j int) bool { return ratioKinds[i] < ratioKinds[j] })
+
+	var checks []*thresholdCheck
+	for _, room := range names {
+		summarized := make(map[TrackKind]bool)
+		for _, s := range rooms[room] {
+			summarized[s.kind] = true
+			if th.MaxLatency > 0 && s.latencyCount > 0 {
+				checks = append(checks, &thresholdCheck{
+					room:   room,
+					name:   fmt.Sprintf("%s latency", s.kind),
+					value:  s.avgLatency().String(),
+					limit:  th.MaxLatency.String(),
+					failed: s.avgLatency() > th.MaxLatency,
+				})
+			}
+
+			if th.MaxDropPercent != nil && s.tracks > 0 {
+				checks = append(checks, &thresholdCheck{
+					room:   room,
+					name:   fmt.Sprintf("%s dropped", s.kind),
+					value:  fmt.Sprintf("%.3f%%", s.dropPercent()),
+					limit:  fmt.Sprintf("%.3f%%", *th.MaxDropPercent),
+					failed: s.dropPercent() > *th.MaxDropPercent,
+				})
+			}
+
+			if minRatio, ok := th.MinBitrateRatio[s.kind]; ok {
+				c := &thresholdCheck{
+					room:  room,
+					name:  fmt.Sprintf("%s bitrate ratio", s.kind),
+					value: "-",
+					limit: fmt.Sprintf("%.2f", minRatio),
+				}
+				if ratio, known := s.bitrateRatio(); known {
+					c.value = fmt.Sprintf("%.2f", ratio)
+					c.failed = ratio < minRatio
+				} else if s.tracks > 0 {
+					// no track had a published bitrate to compare with, the threshold can't pass
+					c.value = "unknown"
+					c.failed = true
+				} else {
+					// nothing was received at all
+					c.failed = minRatio > 0
+				}
+				checks = append(checks, c)
+			}
+
+			// every failed tester is counted once per kind, video is always summarized
+			if th.MaxErrors != nil && s.kind == TrackKindVideo {
+				checks = append(checks, &thresholdCheck{
+					room:   room,
+					name:   "errors",
+					value:  strconv.FormatInt(s.errCount, 10),
+					limit:  strconv.Itoa(*th.MaxErrors),
+					failed: s.errCount > int64(*th.MaxErrors),
+				})
+			}
+		}
+
+		// kinds the room has no summary for, e.g. data without data publishers
+		for _, kind := range ratioKinds {
+			if !summarized[kind] {
+				checks = append(checks, &thresholdCheck{
+					room:   room,
+					name:   fmt.Sprintf("%s bitrate ratio", kind),
+					value:  "-",
+					limit:  fmt.Sprintf("%.2f", th.MinBitrateRatio[kind]),
+					failed: true,
+				})
+			}
+		}
+	}
+
+	return checks
+}
+
+func printThresholds(out io.Writer, checks []*thresholdCheck) {
+	if len(checks) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 1, 1, 1, ' ', 0)
+	_, _ = fmt.Fprint(w, "\nThresholds\t| Room\t| Check\t| Value\t| Limit\t| Result\n")
+	for _, c := range checks {
+		result := "pass"
+		if c.failed {
+			result = "FAIL"
+		}
+		_, _ = fmt.Fprintf(w, "\t| %s\t| %s\t| %s\t| %s\t| %s\n", c.room, c.name, c.value, c.limit, result)
+	}
+	_ = w.Flush()
+}
+
+// thresholdError returns a ThresholdError listing failed checks, nil when all passed
+func thresholdError(checks []*thresholdCheck) error {
+	var failures []string
+	for _, c := range checks {
+		if c.failed {
+			failures = append(failures, c.String())
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &ThresholdError{Failures: failures}
+}
+
+// checkResults checks thresholds for a finished test and writes the JUnit report when requested
+func (t *LoadTest) checkResults(stats map[string]map[string]*testerStats) error {
+	if !t.Params.Thresholds.enabled() && t.Params.JUnitFile == "" {
+		return nil
+	}
+
+	rooms := summarizeRooms(stats, t.Params.DataPublishers > 0, t.Params.WithAudio)
+	checks := checkThresholds(t.Params.Thresholds, rooms)
+	printThresholds(t.Out, checks)
+
+	if t.Params.JUnitFile != "" {
+		if err := writeJUnit(t.Params.JUnitFile, "load-test", rooms, checks); err != nil {
+			return err
+		}
+	}
+
+	return thresholdError(checks)
+}
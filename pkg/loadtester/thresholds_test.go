@@ -0,0 +1,96 @@
+package loadtester
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	maxDrop := 1.0
+	maxErrors := 0
+	th := Thresholds{
+		MaxLatency:      100 * time.Millisecond,
+		MaxDropPercent:  &maxDrop,
+		MinBitrateRatio: map[TrackKind]float64{TrackKindVideo: 0.8},
+		MaxErrors:       &maxErrors,
+	}
+
+	rooms := map[string][]*summary{
+		"good": {{
+			kind:          TrackKindVideo,
+			tracks:        2,
+			packets:       1000,
+			dropped:       5,
+			latency:       int64(50 * time.Millisecond * 10),
+			latencyCount:  10,
+			comparedBytes: 900,
+			expectedBytes: 1000,
+		}},
+		"bad": {{
+			kind:          TrackKindVideo,
+			tracks:        2,
+			packets:       900,
+			dropped:       100,
+			latency:       int64(200 * time.Millisecond * 10),
+			latencyCount:  10,
+			comparedBytes: 500,
+			expectedBytes: 1000,
+			errCount:      1,
+		}},
+	}
+
+	checks := checkThresholds(th, rooms)
+	require.Len(t, checks, 8)
+	for _, c := range checks {
+		require.Equal(t, c.room == "bad", c.failed, c.String())
+	}
+
+	err := thresholdError(checks)
+	require.IsType(t, &ThresholdError{}, err)
+	require.Len(t, err.(*ThresholdError).Failures, 4)
+
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	require.NoError(t, writeJUnit(path, "load-test", rooms, checks))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(string(content), `<testsuites name="load-test" tests="8" failures="4">`))
+}
+
+func TestParseBitrateRatio(t *testing.T) {
+	kind, ratio, err := ParseBitrateRatio("video:0.8")
+	require.NoError(t, err)
+	require.Equal(t, TrackKindVideo, kind)
+	require.Equal(t, 0.8, ratio)
+
+	_, _, err = ParseBitrateRatio("screen:0.8")
+	require.Error(t, err)
+	// audio has no published bitrate
+	_, _, err = ParseBitrateRatio("audio:0.8")
+	require.Error(t, err)
+	_, _, err = ParseBitrateRatio("video")
+	require.Error(t, err)
+}
+
+func TestCheckThresholdsUnknown(t *testing.T) {
+	th := Thresholds{
+		MinBitrateRatio: map[TrackKind]float64{TrackKindVideo: 0.8, TrackKindData: 0.5},
+	}
+
+	// video was received, but none of it was published at a known bitrate, and there was no data
+	rooms := map[string][]*summary{
+		"room": {{kind: TrackKindVideo, tracks: 2, packets: 1000, bytes: 100_000}},
+	}
+
+	checks := checkThresholds(th, rooms)
+	require.Len(t, checks, 2)
+	require.Equal(t, "video bitrate ratio", checks[0].name)
+	require.Equal(t, "unknown", checks[0].value)
+	require.True(t, checks[0].failed)
+	require.Equal(t, "data bitrate ratio", checks[1].name)
+	require.True(t, checks[1].failed)
+}
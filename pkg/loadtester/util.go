@@ -39,11 +39,29 @@ func formatStrings(
 	return
 }
 
+// formatRTPStats shows sequence number based loss, duplicates, reordering and jitter, tracks without RTP show dashes
+func formatRTPStats(s *trackStats) (lost, dups, outOfOrder, jitter string) {
+	expected := s.rtpExpected.Load()
+	if expected == 0 {
+		return " - ", " - ", " - ", " - "
+	}
+
+	lost = fmt.Sprintf("%d (%s%%)", s.rtpLost(), formatPercentage(s.rtpLost(), expected))
+	dups = fmt.Sprint(s.rtpDuplicates.Load())
+	outOfOrder = fmt.Sprint(s.rtpOutOfOrder.Load())
+	jitter = formatLatency(time.Duration(s.jitter.Load()))
+	return
+}
+
 func formatPercentage(num int64, total int64) string {
 	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", float64(num)/float64(total)*100), "0"), ".")
 }
 
 func formatBitrate(bytes int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return " - "
+	}
+
 	bps := float64(bytes*8) / elapsed.Seconds()
 	if bps < 1000 {
 		return fmt.Sprintf("%dbps", int(bps))
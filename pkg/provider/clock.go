@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// reference minus local time, see SetClockOffset
+var clockOffset atomic.Duration
+
+// SetClockOffset makes send times, and the latencies taken from them, use a reference clock shared
+// with other machines. offset is the reference time minus the local time.
+func SetClockOffset(offset time.Duration) {
+	clockOffset.Store(offset)
+}
+
+// Now is the time of the reference clock, the local time until an offset is set
+func Now() time.Time {
+	return time.Now().Add(clockOffset.Load())
+}
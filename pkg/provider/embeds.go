@@ -2,7 +2,11 @@ package provider
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"strconv"
 
 	"go.uber.org/atomic"
@@ -13,29 +17,43 @@ import (
 const (
 	h264Codec = "h264"
 	vp8Codec  = "vp8"
+	vp9Codec  = "vp9"
+	av1Codec  = "av1"
 )
 
 type videoSpec struct {
-	codec      string
-	prefix     string
+	codec  string
+	prefix string
+	// file of a media directory, embedded resources are found by Name
+	path       string
 	height     int
 	width      int
 	kbps       int
 	fps        int
 	resolution string
 	quality    livekit.VideoQuality
+	// temporal layers of an SVC file whose spatial layers are the ladder, 0 for a ladder of separate files
+	temporalLayers int
 }
 
 func (v *videoSpec) Name() string {
-	ext := "h264"
-	if v.codec == vp8Codec {
-		ext = "ivf"
-	}
 	size := strconv.Itoa(v.height)
 	if v.height > v.width {
 		size = fmt.Sprintf("p%d", v.width)
 	}
-	return fmt.Sprintf("resources/%s_%s_%d.%s", v.prefix, size, v.kbps, ext)
+	return fmt.Sprintf("resources/%s_%s_%d.h264", v.prefix, size, v.kbps)
+}
+
+func (v *videoSpec) open() (io.ReadCloser, error) {
+	if v.path != "" {
+		return os.Open(v.path)
+	}
+
+	f, err := res.Open(v.Name())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s is not included in this build, see pkg/provider/resources/README.md for how to encode it", v.Name())
+	}
+	return f, err
 }
 
 func (v *videoSpec) ToVideoLayer() *livekit.VideoLayer {
@@ -104,6 +122,8 @@ var (
 	videoIndex  atomic.Int64
 	audioIndex  atomic.Int64
 	resolutions map[string][]Ratio
+	// used when no resolution is given, see UseMediaDir
+	defaultResolution = "1080p"
 )
 
 func prepareResolutions() {
@@ -133,9 +153,17 @@ func prepareResolutions() {
 }
 
 func GetVideoResolution(resolution string) []Ratio {
+	if resolution == "" {
+		resolution = defaultResolution
+	}
 	return resolutions[resolution]
 }
 
+// DefaultResolution is the resolution of publishers that were not given one
+func DefaultResolution() string {
+	return defaultResolution
+}
+
 func init() {
 	prepareResolutions()
 
@@ -147,17 +175,29 @@ func init() {
 	}
 }
 
-func getVideoSpecs(videoCodec string, resolution string) []*videoSpec {
-	if videoCodec == "" {
-		videoCodec = h264Codec
+// CheckCodec tells if videos of the codec can be published, an empty codec means the default h264
+func CheckCodec(codec string) error {
+	switch codec {
+	case "", h264Codec, vp8Codec, vp9Codec:
+		return nil
+	case av1Codec:
+		// the peer connections of the server SDK are created with pion's default codecs, which have no AV1
+		return errors.New("av1 is not negotiated by the peer connections of this version of the server SDK, " +
+			"so av1 tracks can neither be published nor received")
+	default:
+		return fmt.Errorf("unsupported video codec %s, choose from h264, vp8, vp9", codec)
 	}
+}
 
+// getVideoSpecs returns the ladder of a codec and resolution, the first registered codec
+// of the resolution is used when none is given, which is h264 for the built-in videos
+func getVideoSpecs(videoCodec string, resolution string) []*videoSpec {
 	if resolution == "" {
-		resolution = "1080p"
+		resolution = defaultResolution
 	}
 
 	for _, spec := range videoSpecs {
-		if spec.codec == videoCodec && spec.resolution == resolution {
+		if (videoCodec == "" || spec.codec == videoCodec) && spec.resolution == resolution {
 			return spec.specs
 		}
 	}
@@ -165,10 +205,55 @@ func getVideoSpecs(videoCodec string, resolution string) []*videoSpec {
 	return nil
 }
 
+// CheckVideo opens the files publishers of the resolution and codec loop over, so that media
+// missing from this build or from a media directory fails a test before anyone joins
+func CheckVideo(resolution string, codec string, simulcast bool) error {
+	specs := getVideoSpecs(codec, resolution)
+	if specs == nil {
+		return missingVideo(codec, resolution)
+	}
+
+	if !simulcast {
+		specs = specs[:1]
+	}
+
+	for _, spec := range specs {
+		f, err := spec.open()
+		if err != nil {
+			return err
+		}
+		_ = f.Close()
+	}
+
+	return nil
+}
+
+func missingVideo(codec, resolution string) error {
+	if codec != "" && codec != h264Codec {
+		return fmt.Errorf("could not find %s video for %s, the built-in videos are h264 and "+
+			"other codecs are read from a media directory", codec, resolution)
+	}
+	return fmt.Errorf("could not find video spec for %s %s", codec, resolution)
+}
+
 func CreateVideoLoopers(resolution string, codecFilter string, simulcast bool) ([]VideoLooper, error) {
 	specs := getVideoSpecs(codecFilter, resolution)
 	if specs == nil {
-		return nil, fmt.Errorf("could not find video spec for %s %s", codecFilter, resolution)
+		return nil, missingVideo(codecFilter, resolution)
+	}
+
+	// the spatial layers of an SVC file are sent in one track, with or without simulcast
+	if specs[0].temporalLayers > 0 {
+		f, err := specs[0].open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		looper, err := NewVP9SVCLooper(f, specs, specs[0].temporalLayers)
+		if err != nil {
+			return nil, err
+		}
+		return []VideoLooper{looper}, nil
 	}
 
 	var loopers []VideoLooper
@@ -178,7 +263,7 @@ func CreateVideoLoopers(resolution string, codecFilter string, simulcast bool) (
 	}
 
 	for _, spec := range specs {
-		f, err := res.Open(spec.Name())
+		f, err := spec.open()
 		if err != nil {
 			return nil, err
 		}
@@ -192,6 +277,10 @@ func CreateVideoLoopers(resolution string, codecFilter string, simulcast bool) (
 			if looper, err = NewVP8VideoLooper(f, spec); err != nil {
 				return nil, err
 			}
+		} else if spec.codec == vp9Codec {
+			if looper, err = NewVP9VideoLooper(f, spec); err != nil {
+				return nil, err
+			}
 		}
 
 		loopers = append(loopers, looper)
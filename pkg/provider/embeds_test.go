@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVideo(t *testing.T) {
+	require.NoError(t, CheckVideo("1080p", h264Codec, true))
+	require.NoError(t, CheckVideo("", "", false))
+
+	// the built-in videos are h264 only
+	err := CheckVideo("720p", vp8Codec, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "media directory")
+
+	require.Error(t, CheckVideo("4k", h264Codec, false))
+}
+
+func TestCheckCodec(t *testing.T) {
+	require.NoError(t, CheckCodec(vp9Codec))
+	require.Error(t, CheckCodec(av1Codec))
+	require.Error(t, CheckCodec("hevc"))
+}
@@ -2,7 +2,6 @@ package provider
 
 import (
 	"bytes"
-	"encoding/binary"
 	"io"
 	"time"
 
@@ -88,12 +87,6 @@ func (l *H264VideoLooper) nextSample(rewindEOF bool) (media.Sample, error) {
 		isFrame = true
 	}
 
-	if isFrame {
-		ts := make([]byte, 8)
-		binary.LittleEndian.PutUint64(ts, uint64(time.Now().UnixNano()))
-		nal.Data = append(nal.Data, ts...)
-	}
-
 	sample.Data = nal.Data
 	if isFrame {
 		sample.Duration = l.frameDuration
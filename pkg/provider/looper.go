@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 
 	"github.com/livekit/protocol/livekit"
@@ -16,3 +17,10 @@ type VideoLooper interface {
 	Looper
 	ToLayer() *livekit.VideoLayer
 }
+
+// SVCLooper is a VideoLooper of a stream with several spatial layers in one track,
+// which it packetizes itself as the SDK's payloaders write no layer indices
+type SVCLooper interface {
+	VideoLooper
+	Payloader() rtp.Payloader
+}
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// ManifestFile is the name of the manifest in a media directory
+const ManifestFile = "manifest.yaml"
+
+// qualities of ladder layers, in the order layers are listed
+var layerQualities = []livekit.VideoQuality{
+	livekit.VideoQuality_HIGH,
+	livekit.VideoQuality_MEDIUM,
+	livekit.VideoQuality_LOW,
+}
+
+// Manifest describes videos kept in a media directory, used instead of the ones built into the binary
+type Manifest struct {
+	Videos []ManifestVideo `yaml:"videos"`
+}
+
+// ManifestVideo is a ladder of one codec, selected by its resolution name like the built-in 1080p
+type ManifestVideo struct {
+	// any name, e.g. 540p-portrait or screen-1080p
+	Resolution string `yaml:"resolution"`
+	Codec      string `yaml:"codec"`
+	// highest quality first, one layer per simulcast track up to three
+	Layers []ManifestLayer `yaml:"layers"`
+	// of a vp9 file with spatial and temporal layers published as one track, e.g. L3T3.
+	// Its layers are then the spatial layers of that file, which they all name.
+	ScalabilityMode string `yaml:"scalability_mode"`
+}
+
+type ManifestLayer struct {
+	// relative to the media directory, .h264 Annex B for h264 and .ivf otherwise
+	File   string `yaml:"file"`
+	Width  int    `yaml:"width"`
+	Height int    `yaml:"height"`
+	// in kbps
+	Bitrate int `yaml:"bitrate"`
+	FPS     int `yaml:"fps"`
+}
+
+// LoadManifest reads and validates the manifest of a media directory
+func LoadManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", dir, err)
+	}
+
+	if err := m.validate(dir); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", dir, err)
+	}
+
+	return m, nil
+}
+
+func (m *Manifest) validate(dir string) error {
+	if len(m.Videos) == 0 {
+		return errors.New("no videos")
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range m.Videos {
+		if v.Resolution == "" {
+			return errors.New("video without resolution")
+		}
+		codec := strings.ToLower(v.Codec)
+		if codec == "" {
+			return fmt.Errorf("%s: codec is required", v.Resolution)
+		}
+		if err := CheckCodec(codec); err != nil {
+			return fmt.Errorf("%s: %w", v.Resolution, err)
+		}
+		if seen[v.Resolution+"/"+codec] {
+			return fmt.Errorf("%s: more than one %s ladder", v.Resolution, codec)
+		}
+		seen[v.Resolution+"/"+codec] = true
+
+		if len(v.Layers) == 0 || len(v.Layers) > len(layerQualities) {
+			return fmt.Errorf("%s: expected 1 to %d layers, got %d", v.Resolution, len(layerQualities), len(v.Layers))
+		}
+		for _, l := range v.Layers {
+			if l.Width <= 0 || l.Height <= 0 || l.Bitrate <= 0 || l.FPS <= 0 {
+				return fmt.Errorf("%s: width, height, bitrate and fps of %s must be positive", v.Resolution, l.File)
+			}
+			if _, err := os.Stat(filepath.Join(dir, l.File)); err != nil {
+				return fmt.Errorf("%s: %w", v.Resolution, err)
+			}
+		}
+		if v.ScalabilityMode != "" {
+			if err := v.validateSVC(codec); err != nil {
+				return fmt.Errorf("%s: %w", v.Resolution, err)
+			}
+		}
+	}
+
+	// ladders of different codecs share a resolution name, so they must agree on its dimensions
+	for name, ratios := range m.resolutions() {
+		for _, v := range m.Videos {
+			if v.Resolution != name {
+				continue
+			}
+			if len(v.Layers) != len(ratios) {
+				return fmt.Errorf("%s: ladders of different codecs do not match", name)
+			}
+			for i, l := range v.Layers {
+				if l.Width != ratios[i].Width || l.Height != ratios[i].Height {
+					return fmt.Errorf("%s: ladders of different codecs do not match", name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *ManifestVideo) validateSVC(codec string) error {
+	if codec != vp9Codec {
+		return fmt.Errorf("scalability_mode is for vp9, not %s", codec)
+	}
+	spatial, _, err := parseScalabilityMode(v.ScalabilityMode)
+	if err != nil {
+		return err
+	}
+	if spatial != len(v.Layers) {
+		return fmt.Errorf("%s has %d spatial layers, got %d layers", v.ScalabilityMode, spatial, len(v.Layers))
+	}
+	for _, l := range v.Layers {
+		if l.File != v.Layers[0].File {
+			return errors.New("layers of a scalability_mode must name the same file")
+		}
+	}
+	return nil
+}
+
+// parseScalabilityMode reads the spatial and temporal layers of a mode like L3T3, up to 3 of each
+func parseScalabilityMode(mode string) (int, int, error) {
+	var spatial, temporal int
+	if _, err := fmt.Sscanf(strings.ToUpper(mode), "L%1dT%1d", &spatial, &temporal); err != nil ||
+		len(mode) != 4 || spatial < 1 || spatial > 3 || temporal < 1 || temporal > 3 {
+		return 0, 0, fmt.Errorf("unsupported scalability_mode %s, expected L1T1 to L3T3", mode)
+	}
+	return spatial, temporal, nil
+}
+
+// Ratios returns the layers of a resolution like GetVideoResolution does, nil when it is not in the manifest
+func (m *Manifest) Ratios(resolution string) []Ratio {
+	return m.resolutions()[resolution]
+}
+
+func (m *Manifest) resolutions() map[string][]Ratio {
+	r := make(map[string][]Ratio)
+	for _, v := range m.Videos {
+		if _, ok := r[v.Resolution]; ok {
+			continue
+		}
+		ratios := make([]Ratio, 0, len(v.Layers))
+		for i, l := range v.Layers {
+			ratios = append(ratios, Ratio{Width: l.Width, Height: l.Height, Quality: layerQualities[i]})
+		}
+		r[v.Resolution] = ratios
+	}
+
+	return r
+}
+
+// UseMediaDir replaces the built-in videos by those of the manifest in dir.
+// The first video of the manifest becomes the default resolution.
+func UseMediaDir(dir string) error {
+	m, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	specs := make([]videoSpecParam, 0, len(m.Videos))
+	for _, v := range m.Videos {
+		codec := strings.ToLower(v.Codec)
+		param := videoSpecParam{
+			codec:      codec,
+			resolution: v.Resolution,
+		}
+		var temporalLayers int
+		if v.ScalabilityMode != "" {
+			_, temporalLayers, _ = parseScalabilityMode(v.ScalabilityMode) // already checked by validate
+		}
+		for i, l := range v.Layers {
+			param.specs = append(param.specs, &videoSpec{
+				codec:          codec,
+				path:           filepath.Join(dir, l.File),
+				height:         l.Height,
+				width:          l.Width,
+				kbps:           l.Bitrate,
+				fps:            l.FPS,
+				resolution:     v.Resolution,
+				quality:        layerQualities[i],
+				temporalLayers: temporalLayers,
+			})
+		}
+		specs = append(specs, param)
+	}
+
+	videoSpecs = specs
+	resolutions = m.resolutions()
+	defaultResolution = m.Videos[0].Resolution
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// mediaDir writes a manifest and empty files for it, returning the directory
+func mediaDir(t *testing.T, manifest string, files ...string) string {
+	dir := t.TempDir()
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0644))
+	return dir
+}
+
+const portraitManifest = `
+videos:
+  - resolution: 540p-portrait
+    codec: h264
+    layers:
+      - {file: portrait_960.h264, width: 540, height: 960, bitrate: 1500, fps: 30}
+      - {file: portrait_540.h264, width: 304, height: 540, bitrate: 500, fps: 30}
+  - resolution: 540p-portrait
+    codec: VP8
+    layers:
+      - {file: portrait_960.ivf, width: 540, height: 960, bitrate: 1500, fps: 30}
+      - {file: portrait_540.ivf, width: 304, height: 540, bitrate: 500, fps: 30}
+  - resolution: screen
+    codec: h264
+    layers:
+      - {file: screen.h264, width: 1920, height: 1080, bitrate: 2500, fps: 5}
+`
+
+var portraitFiles = []string{"portrait_960.h264", "portrait_540.h264", "portrait_960.ivf", "portrait_540.ivf", "screen.h264"}
+
+func TestLoadManifest(t *testing.T) {
+	m, err := LoadManifest(mediaDir(t, portraitManifest, portraitFiles...))
+	require.NoError(t, err)
+	require.Len(t, m.Videos, 3)
+
+	require.Equal(t, []Ratio{
+		{Width: 540, Height: 960, Quality: livekit.VideoQuality_HIGH},
+		{Width: 304, Height: 540, Quality: livekit.VideoQuality_MEDIUM},
+	}, m.Ratios("540p-portrait"))
+	require.Len(t, m.Ratios("screen"), 1)
+	require.Nil(t, m.Ratios("1080p"))
+
+	_, err = LoadManifest(t.TempDir())
+	require.Error(t, err)
+}
+
+func TestManifestValidate(t *testing.T) {
+	layer := "{file: a.h264, width: 640, height: 360, bitrate: 500, fps: 30}"
+	cases := map[string]string{
+		"no videos":       "videos: []",
+		"unknown field":   "videos: [{resolution: a, codec: h264, layers: [" + layer + "]}]\nvideo: []",
+		"no resolution":   "videos: [{codec: h264, layers: [" + layer + "]}]",
+		"no codec":        "videos: [{resolution: a, layers: [" + layer + "]}]",
+		"codec":           "videos: [{resolution: a, codec: hevc, layers: [" + layer + "]}]",
+		"unpublishable":   "videos: [{resolution: a, codec: av1, layers: [" + layer + "]}]",
+		"svc codec":       "videos: [{resolution: a, codec: h264, scalability_mode: L1T3, layers: [" + layer + "]}]",
+		"svc mode":        "videos: [{resolution: a, codec: vp9, scalability_mode: L4T1, layers: [" + layer + "]}]",
+		"svc layers":      "videos: [{resolution: a, codec: vp9, scalability_mode: L2T3, layers: [" + layer + "]}]",
+		"svc files":       "videos: [{resolution: a, codec: vp9, scalability_mode: L2T1, layers: [" + layer + ", {file: a.ivf, width: 320, height: 180, bitrate: 200, fps: 30}]}]",
+		"duplicate":       "videos: [{resolution: a, codec: h264, layers: [" + layer + "]}, {resolution: a, codec: H264, layers: [" + layer + "]}]",
+		"no layers":       "videos: [{resolution: a, codec: h264, layers: []}]",
+		"too many layers": "videos: [{resolution: a, codec: h264, layers: [" + layer + ", " + layer + ", " + layer + ", " + layer + "]}]",
+		"zero fps":        "videos: [{resolution: a, codec: h264, layers: [{file: a.h264, width: 640, height: 360, bitrate: 500}]}]",
+		"missing file":    "videos: [{resolution: a, codec: h264, layers: [{file: b.h264, width: 640, height: 360, bitrate: 500, fps: 30}]}]",
+		"ladders differ": "videos: [{resolution: a, codec: h264, layers: [" + layer + "]}, " +
+			"{resolution: a, codec: vp8, layers: [{file: a.h264, width: 320, height: 180, bitrate: 500, fps: 30}]}]",
+	}
+
+	for name, manifest := range cases {
+		_, err := LoadManifest(mediaDir(t, manifest, "a.h264", "a.ivf"))
+		require.Error(t, err, name)
+	}
+}
+
+func TestUseMediaDir(t *testing.T) {
+	specs, ratios, defaultRes := videoSpecs, resolutions, defaultResolution
+	defer func() {
+		videoSpecs, resolutions, defaultResolution = specs, ratios, defaultRes
+	}()
+
+	dir := mediaDir(t, portraitManifest, portraitFiles...)
+	require.NoError(t, UseMediaDir(dir))
+
+	// the first video is the default, built-in resolutions are gone
+	require.Equal(t, "540p-portrait", DefaultResolution())
+	require.Len(t, GetVideoResolution(""), 2)
+	require.Nil(t, GetVideoResolution("1080p"))
+
+	// h264 is listed first
+	s := getVideoSpecs("", "540p-portrait")
+	require.Len(t, s, 2)
+	require.Equal(t, h264Codec, s[0].codec)
+	require.Equal(t, filepath.Join(dir, "portrait_960.h264"), s[0].path)
+	require.Equal(t, uint32(1_500_000), s[0].ToVideoLayer().Bitrate)
+	require.Equal(t, livekit.VideoQuality_MEDIUM, s[1].quality)
+
+	s = getVideoSpecs(vp8Codec, "540p-portrait")
+	require.Len(t, s, 2)
+	require.Equal(t, filepath.Join(dir, "portrait_540.ivf"), s[1].path)
+	require.Nil(t, getVideoSpecs(vp8Codec, "screen"))
+
+	require.NoError(t, CheckVideo("screen", "", false))
+	require.Error(t, CheckVideo("1080p", h264Codec, false))
+
+	// an invalid manifest leaves the videos in use alone
+	require.Error(t, UseMediaDir(t.TempDir()))
+	require.Equal(t, "540p-portrait", DefaultResolution())
+}
+
+func TestUseMediaDirSVC(t *testing.T) {
+	specs, ratios, defaultRes := videoSpecs, resolutions, defaultResolution
+	defer func() {
+		videoSpecs, resolutions, defaultResolution = specs, ratios, defaultRes
+	}()
+
+	manifest := `
+videos:
+  - resolution: 720p
+    codec: vp9
+    scalability_mode: l2t3
+    layers:
+      - {file: svc.ivf, width: 1280, height: 720, bitrate: 1500, fps: 30}
+      - {file: svc.ivf, width: 640, height: 360, bitrate: 500, fps: 30}
+`
+	require.NoError(t, UseMediaDir(mediaDir(t, manifest, "svc.ivf")))
+
+	// one track of both spatial layers, with or without simulcast
+	for _, simulcast := range []bool{true, false} {
+		loopers, err := CreateVideoLoopers("720p", vp9Codec, simulcast)
+		require.NoError(t, err)
+		require.Len(t, loopers, 1)
+		require.Implements(t, (*SVCLooper)(nil), loopers[0])
+		require.Equal(t, uint32(1280), loopers[0].ToLayer().Width)
+	}
+}
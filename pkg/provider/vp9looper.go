@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"io"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// VP9VideoLooper publishes a VP9 stream of a single layer, through the SDK's payloader like the other loopers
+type VP9VideoLooper struct {
+	*ivfLooper
+}
+
+func NewVP9VideoLooper(input io.Reader, spec *videoSpec) (*VP9VideoLooper, error) {
+	l, err := newIVFLooper(input, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VP9VideoLooper{ivfLooper: l}, nil
+}
+
+func (l *VP9VideoLooper) Codec() webrtc.RTPCodecCapability {
+	return vp9Capability()
+}
+
+// VP9SVCLooper loops over a VP9 stream with spatial and temporal layers, as encoded by libvpx's
+// vp9_spatial_svc_encoder. It is published as a single track packetized by its own payloader.
+type VP9SVCLooper struct {
+	*ivfLooper
+	specs          []*videoSpec
+	temporalLayers int
+}
+
+// NewVP9SVCLooper reads a stream whose spatial layers are specs, highest first
+func NewVP9SVCLooper(input io.Reader, specs []*videoSpec, temporalLayers int) (*VP9SVCLooper, error) {
+	l, err := newIVFLooper(input, specs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &VP9SVCLooper{ivfLooper: l, specs: specs, temporalLayers: temporalLayers}, nil
+}
+
+func (l *VP9SVCLooper) Codec() webrtc.RTPCodecCapability {
+	return vp9Capability()
+}
+
+// Payloader returns a new payloader for a track of the looper
+func (l *VP9SVCLooper) Payloader() rtp.Payloader {
+	return NewVP9SVCPayloader(l.specs, l.temporalLayers)
+}
+
+func vp9Capability() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{
+		MimeType:    "video/vp9",
+		ClockRate:   90000,
+		SDPFmtpLine: "profile-id=0",
+		RTCPFeedback: []webrtc.RTCPFeedback{
+			{Type: webrtc.TypeRTCPFBNACK},
+			{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+		},
+	}
+}
@@ -0,0 +1,158 @@
+package provider
+
+// temporal layer of each picture since a keyframe, by number of temporal layers,
+// the patterns of libvpx's vp9_spatial_svc_encoder
+var temporalPatterns = map[int][]uint8{
+	1: {0},
+	2: {0, 1},
+	3: {0, 2, 1, 2},
+}
+
+// VP9SVCPayloader packetizes VP9 pictures of a stream with spatial and temporal layers into RTP payloads
+// in the non-flexible mode of the VP9 RTP payload format, with the layer indices the SFU selects layers by.
+// Pion's VP9Payloader, which the server SDK uses, writes no layer indices and no scalability structure.
+// A picture is a superframe holding one frame per spatial layer, lowest layer first.
+type VP9SVCPayloader struct {
+	// of the spatial layers, lowest first
+	widths  []uint16
+	heights []uint16
+	pattern []uint8
+
+	pictureID uint16
+	tl0PicIdx uint8
+	// pictures since the last keyframe
+	pictures int
+}
+
+// NewVP9SVCPayloader is for a stream of the spatial layers of specs, highest first like in a ladder,
+// and of 1 to 3 temporal layers
+func NewVP9SVCPayloader(specs []*videoSpec, temporalLayers int) *VP9SVCPayloader {
+	p := &VP9SVCPayloader{pattern: temporalPatterns[temporalLayers]}
+	for i := len(specs) - 1; i >= 0; i-- {
+		p.widths = append(p.widths, uint16(specs[i].width))
+		p.heights = append(p.heights, uint16(specs[i].height))
+	}
+	return p
+}
+
+// Payload implements rtp.Payloader, it is given one picture at a time
+func (p *VP9SVCPayloader) Payload(mtu uint16, payload []byte) [][]byte {
+	frames := vp9Frames(payload)
+	if len(frames) == 0 {
+		return nil
+	}
+
+	keyframe := vp9Keyframe(frames[0])
+	if keyframe {
+		p.pictures = 0
+	}
+	tid := p.pattern[p.pictures%len(p.pattern)]
+	p.pictures++
+	if tid == 0 {
+		p.tl0PicIdx++
+	}
+	defer func() {
+		p.pictureID = (p.pictureID + 1) & 0x7fff
+	}()
+
+	var payloads [][]byte
+	for sid, frame := range frames {
+		for pos := 0; pos < len(frame); {
+			header := p.descriptor(keyframe, pos == 0, sid, tid)
+			if int(mtu) <= len(header) {
+				return nil
+			}
+			size := len(frame) - pos
+			if room := int(mtu) - len(header); size > room {
+				size = room
+			}
+			if pos+size == len(frame) {
+				header[0] |= 0x04 // E
+			}
+
+			out := make([]byte, len(header)+size)
+			copy(out, header)
+			copy(out[len(header):], frame[pos:pos+size])
+			payloads = append(payloads, out)
+			pos += size
+		}
+	}
+
+	return payloads
+}
+
+// descriptor writes the payload descriptor of a packet of a layer frame, without the E bit
+func (p *VP9SVCPayloader) descriptor(keyframe, begin bool, sid int, tid uint8) []byte {
+	// I and L, a 15 bit picture ID and the layer indices with TL0PICIDX
+	d := []byte{0xa0, 0x80 | byte(p.pictureID>>8), byte(p.pictureID), 0, p.tl0PicIdx}
+	if !keyframe {
+		d[0] |= 0x40 // P, the picture refers to earlier ones
+	}
+	if begin {
+		d[0] |= 0x08 // B
+	}
+	d[3] = tid<<5 | byte(sid)<<1
+	if sid > 0 {
+		d[3] |= 0x01 // D, upper spatial layers refer to the lower ones
+	}
+
+	// the scalability structure with the size of each spatial layer starts a keyframe
+	if keyframe && begin && sid == 0 {
+		d[0] |= 0x02 // V
+		d = append(d, byte(len(p.widths)-1)<<5|0x10)
+		for i := range p.widths {
+			d = append(d, byte(p.widths[i]>>8), byte(p.widths[i]), byte(p.heights[i]>>8), byte(p.heights[i]))
+		}
+	}
+
+	return d
+}
+
+// vp9Frames splits a superframe into its frames, other data is a single frame
+func vp9Frames(data []byte) [][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// the superframe index ends with a marker byte that it also starts with
+	marker := data[len(data)-1]
+	if marker&0xe0 != 0xc0 {
+		return [][]byte{data}
+	}
+	count := int(marker&0x07) + 1
+	sizeBytes := int(marker>>3&0x03) + 1
+	indexSize := 2 + sizeBytes*count
+	if len(data) < indexSize || data[len(data)-indexSize] != marker {
+		return [][]byte{data}
+	}
+
+	index := data[len(data)-indexSize+1:]
+	frames := make([][]byte, 0, count)
+	pos := 0
+	for i := 0; i < count; i++ {
+		size := 0
+		for b := 0; b < sizeBytes; b++ {
+			size |= int(index[i*sizeBytes+b]) << (8 * b)
+		}
+		if pos+size > len(data)-indexSize {
+			return [][]byte{data}
+		}
+		frames = append(frames, data[pos:pos+size])
+		pos += size
+	}
+
+	return frames
+}
+
+// vp9Keyframe reads show_existing_frame and frame_type of the uncompressed header of a frame
+func vp9Keyframe(frame []byte) bool {
+	// frame_marker, profile_low_bit, profile_high_bit, profile 3 has a reserved bit after them
+	if len(frame) == 0 || frame[0]>>6 != 2 {
+		return false
+	}
+	shift := 3
+	if frame[0]&0x30 == 0x30 {
+		shift = 2
+	}
+	return frame[0]>>shift&0x01 == 0 && frame[0]>>(shift-1)&0x01 == 0
+}
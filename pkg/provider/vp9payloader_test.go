@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/require"
+)
+
+// superframe joins frames with a superframe index of 2 byte sizes
+func superframe(frames ...[]byte) []byte {
+	marker := byte(0xc8 | (len(frames) - 1))
+	var data []byte
+	for _, f := range frames {
+		data = append(data, f...)
+	}
+	data = append(data, marker)
+	for _, f := range frames {
+		data = append(data, byte(len(f)), byte(len(f)>>8))
+	}
+	return append(data, marker)
+}
+
+// vp9Frame is a profile 0 frame of size bytes, a keyframe or an inter frame
+func vp9Frame(key bool, size int) []byte {
+	f := make([]byte, size)
+	f[0] = 0x82
+	if !key {
+		f[0] |= 0x04
+	}
+	return f
+}
+
+func TestVP9Frames(t *testing.T) {
+	require.Nil(t, vp9Frames(nil))
+
+	frames := vp9Frames(superframe(vp9Frame(true, 3), vp9Frame(false, 300)))
+	require.Len(t, frames, 2)
+	require.Len(t, frames[0], 3)
+	require.Len(t, frames[1], 300)
+
+	// a frame without index, and one whose last byte looks like a marker
+	require.Len(t, vp9Frames(vp9Frame(false, 10)), 1)
+	require.Len(t, vp9Frames([]byte{0x82, 0x00, 0xc8}), 1)
+
+	require.True(t, vp9Keyframe(vp9Frame(true, 1)))
+	require.False(t, vp9Keyframe(vp9Frame(false, 1)))
+	require.False(t, vp9Keyframe([]byte{0x00}))
+}
+
+func TestVP9SVCPayloader(t *testing.T) {
+	specs := []*videoSpec{{width: 1280, height: 720}, {width: 640, height: 360}}
+	p := NewVP9SVCPayloader(specs, 2)
+
+	var parsed []*codecs.VP9Packet
+	parse := func(payloads [][]byte) {
+		parsed = parsed[:0]
+		for _, payload := range payloads {
+			require.LessOrEqual(t, len(payload), 1188)
+			pkt := &codecs.VP9Packet{}
+			_, err := pkt.Unmarshal(payload)
+			require.NoError(t, err)
+			parsed = append(parsed, pkt)
+		}
+	}
+
+	// a keyframe whose upper layer needs two packets
+	parse(p.Payload(1188, superframe(vp9Frame(true, 100), vp9Frame(false, 2000))))
+	require.Len(t, parsed, 3)
+	base := parsed[0]
+	require.True(t, base.I && base.L && base.B && base.E && base.V && !base.P && !base.F)
+	require.Equal(t, []uint16{640, 1280}, base.Width)
+	require.Equal(t, []uint16{360, 720}, base.Height)
+	require.Equal(t, uint8(0), base.SID)
+	require.Equal(t, uint8(0), base.TID)
+	require.False(t, base.D)
+
+	require.True(t, parsed[1].B && !parsed[1].E && !parsed[1].V)
+	require.True(t, !parsed[2].B && parsed[2].E)
+	for _, pkt := range parsed[1:] {
+		require.Equal(t, uint8(1), pkt.SID)
+		require.True(t, pkt.D)
+		require.Equal(t, base.PictureID, pkt.PictureID)
+		require.Equal(t, base.TL0PICIDX, pkt.TL0PICIDX)
+	}
+
+	// the next picture is of the upper temporal layer and refers to the keyframe
+	parse(p.Payload(1188, superframe(vp9Frame(false, 10), vp9Frame(false, 10))))
+	require.Len(t, parsed, 2)
+	require.True(t, parsed[0].P && !parsed[0].V)
+	require.Equal(t, uint8(1), parsed[0].TID)
+	require.Equal(t, base.PictureID+1, parsed[0].PictureID)
+	require.Equal(t, base.TL0PICIDX, parsed[0].TL0PICIDX)
+
+	// then the base temporal layer again
+	parse(p.Payload(1188, superframe(vp9Frame(false, 10), vp9Frame(false, 10))))
+	require.Equal(t, uint8(0), parsed[0].TID)
+	require.Equal(t, base.TL0PICIDX+1, parsed[0].TL0PICIDX)
+
+	require.Nil(t, p.Payload(8, superframe(vp9Frame(true, 10))))
+}